@@ -0,0 +1,206 @@
+package glot
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Axis names a gnuplot axis.
+type Axis string
+
+const (
+	AxisX  Axis = "x"
+	AxisY  Axis = "y"
+	AxisZ  Axis = "z"
+	AxisX2 Axis = "x2"
+	AxisY2 Axis = "y2"
+	AxisCB Axis = "cb" // color box of palette based plots
+	AxisR  Axis = "r"  // radial axis of polar plots
+	AxisT  Axis = "t"  // parameter of parametric and polar plots
+)
+
+func (axis Axis) valid() bool {
+	switch axis {
+	case AxisX, AxisY, AxisZ, AxisX2, AxisY2, AxisCB, AxisR, AxisT:
+		return true
+	}
+	return false
+}
+
+// Axes is a pair of axes a point group is drawn against.
+type Axes string
+
+const (
+	AxesX1Y1 Axes = "x1y1" // the bottom and left axes, used by default
+	AxesX1Y2 Axes = "x1y2" // the bottom and right axes
+	AxesX2Y1 Axes = "x2y1" // the top and left axes
+	AxesX2Y2 Axes = "x2y2" // the top and right axes
+)
+
+func (axes Axes) valid() bool {
+	switch axes {
+	case AxesX1Y1, AxesX1Y2, AxesX2Y1, AxesX2Y2:
+		return true
+	}
+	return false
+}
+
+type boundKind int
+
+const (
+	boundAuto boundKind = iota
+	boundValue
+	boundTime
+)
+
+// Bound is one end of an axis range.
+// The zero Bound lets gnuplot autoscale that end of the axis.
+type Bound struct {
+	kind  boundKind
+	value float64
+	time  time.Time
+}
+
+// Auto returns a bound autoscaled by gnuplot.
+func Auto() Bound {
+	return Bound{}
+}
+
+// At returns a bound at the given value.
+func At(value float64) Bound {
+	return Bound{kind: boundValue, value: value}
+}
+
+// AtTime returns a bound at the given time, for axes showing time data.
+func AtTime(t time.Time) Bound {
+	return Bound{kind: boundTime, time: t}
+}
+
+func (bound Bound) auto() bool {
+	return bound.kind == boundAuto
+}
+
+// format returns the bound as used in a range, times are shown in loc.
+func (bound Bound) format(loc *time.Location) string {
+	switch bound.kind {
+	case boundValue:
+		return formatFloat(bound.value)
+	case boundTime:
+		return strconv.FormatFloat(epochSeconds(bound.time, loc), 'f', -1, 64)
+	default:
+		return "*"
+	}
+}
+
+// Range is the range of an axis.
+//
+// Usage
+//
+//	glot.Range{Min: glot.At(0.001), Max: glot.At(0.5)} // [0.001:0.5]
+//	glot.Range{Max: glot.At(100)}                      // [*:100]
+//	glot.Range{Reverse: true}                          // [*:*] reverse
+type Range struct {
+	Min, Max  Bound
+	Reverse   bool // reverse the direction of the axis
+	Writeback bool // keep the range computed by autoscaling for Restore
+	Restore   bool // bring back the range kept by Writeback, Min and Max are ignored
+}
+
+// command returns the command setting the range on the axis.
+// Time bounds are shown in loc.
+func (r Range) command(axis Axis, loc *time.Location) string {
+	if r.Restore {
+		return fmt.Sprintf("set %srange restore", axis)
+	}
+	from, to := r.Min, r.Max
+	reverse := ""
+	if r.Reverse {
+		if from.auto() || to.auto() {
+			reverse = " reverse"
+		} else {
+			from, to = to, from
+		}
+	}
+	writeback := ""
+	if r.Writeback {
+		writeback = " writeback"
+	}
+	return fmt.Sprintf("set %srange [%s:%s]%s%s",
+		axis, from.format(loc), to.format(loc), reverse, writeback)
+}
+
+// SetRange sets the range of any axis, including the secondary x2 and y2
+// axes, the color box and the polar axes.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.SetRange(glot.AxisY, glot.Range{Min: glot.At(0.001), Max: glot.At(0.5)})
+//	plot.SetRange(glot.AxisX, glot.Range{Max: glot.At(100)})
+func (plot *plot) SetRange(axis Axis, r Range) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.setRange(axis, r)
+}
+
+func (plot *plot) setRange(axis Axis, r Range) error {
+	if !axis.valid() {
+		return &GnuplotError{err: fmt.Sprintf("unknown axis '%s'", axis)}
+	}
+	return plot.set(string(axis)+"range", r.command(axis, plot.location))
+}
+
+// SetX2Tics shows tics on the secondary x-axis at the top of the plot, so it
+// gets its own scale instead of mirroring the x-axis.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.SetX2Tics()
+func (plot *plot) SetX2Tics() error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.set("x2tics", "set xtics nomirror\nset x2tics")
+}
+
+// SetY2Tics shows tics on the secondary y-axis at the right of the plot, so
+// it gets its own scale instead of mirroring the y-axis.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.SetY2Tics()
+func (plot *plot) SetY2Tics() error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.set("y2tics", "set ytics nomirror\nset y2tics")
+}
+
+// SetPointGroupAxes binds a point group to a pair of axes, e.g. to draw it
+// against the secondary y-axis. Only 2 dimensional plots have secondary axes.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.AddPointGroup("latency", glot.StyleLines, latency)
+//	plot.AddPointGroup("throughput", glot.StyleLines, throughput)
+//	plot.SetY2Tics()
+//	plot.SetPointGroupAxes("throughput", glot.AxesX1Y2)
+func (plot *plot) SetPointGroupAxes(name string, axes Axes) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	pointGroup, exists := plot.pointGroup[name]
+	if !exists {
+		return &GnuplotError{err: fmt.Sprintf("A curve with name %s does not exist.", name)}
+	}
+	if !axes.valid() {
+		return &GnuplotError{err: fmt.Sprintf("unknown axes '%s'", axes)}
+	}
+	if plot.dimensions == 3 {
+		return &GnuplotError{err: "3 dimensional plots have no secondary axes"}
+	}
+	pointGroup.options.Axes = axes
+	return plot.replot()
+}
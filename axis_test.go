@@ -0,0 +1,36 @@
+package glot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRangeCommand(t *testing.T) {
+	cet := time.FixedZone("CET", 3600)
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		axis Axis
+		r    Range
+		loc  *time.Location
+		want string
+	}{
+		{"values", AxisX, Range{Min: At(0.001), Max: At(0.5)}, time.UTC, "set xrange [0.001:0.5]"},
+		{"open ended", AxisY2, Range{Max: At(100)}, time.UTC, "set y2range [*:100]"},
+		{"reversed", AxisY, Range{Min: At(-1), Max: At(2), Reverse: true}, time.UTC, "set yrange [2:-1]"},
+		{"reversed autoscaling", AxisCB, Range{Min: At(0), Reverse: true}, time.UTC, "set cbrange [0:*] reverse"},
+		{"writeback", AxisX, Range{Writeback: true}, time.UTC, "set xrange [*:*] writeback"},
+		{"reversed writeback", AxisX, Range{Max: At(5), Reverse: true, Writeback: true}, time.UTC, "set xrange [*:5] reverse writeback"},
+		{"restore", AxisX, Range{Min: At(1), Max: At(2), Restore: true}, time.UTC, "set xrange restore"},
+		{"times", AxisX, Range{Min: AtTime(start), Max: AtTime(start.Add(time.Hour))}, time.UTC, "set xrange [1709294400:1709298000]"},
+		// the times are shifted to read as wall clock times of the location
+		{"times in a location", AxisX, Range{Min: AtTime(start)}, cet, "set xrange [1709298000:*]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.r.command(test.axis, test.loc); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
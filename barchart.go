@@ -0,0 +1,285 @@
+package glot
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// BarSeries is a named series of a bar chart, one value per category.
+type BarSeries struct {
+	Name   string
+	Values any                // one number per category, in any format accepted by AddPointGroup for 1 dimension
+	Errors any                // half lengths of the error whiskers, one per category, no whiskers if nil
+	Style  []PointGroupOption // color, fill and the other options of the bars
+}
+
+// BarMode selects how the bars of the series are placed.
+type BarMode int
+
+const (
+	BarsClustered BarMode = iota // the bars of a category side by side
+	BarsStacked                  // the bars of a category on top of each other
+)
+
+// BarChartOptions describes how a bar chart is drawn.
+type BarChartOptions struct {
+	Mode        BarMode
+	Gap         float64 // space between the bars of neighbouring categories, in bar widths
+	Horizontal  bool    // draw the bars from left to right, the categories along the y axis
+	LabelFormat string  // printf like format of the values written on the bars, no labels if empty
+}
+
+// BarChartOption changes the options of a bar chart.
+type BarChartOption func(*BarChartOptions)
+
+// WithBarMode sets how the bars of the series are placed.
+func WithBarMode(mode BarMode) BarChartOption {
+	return func(opts *BarChartOptions) {
+		opts.Mode = mode
+	}
+}
+
+// WithGap sets the space between the bars of neighbouring categories, in bar
+// widths. The default gap is 1.
+func WithGap(gap float64) BarChartOption {
+	return func(opts *BarChartOptions) {
+		opts.Gap = gap
+	}
+}
+
+// WithHorizontalBars draws the bars from left to right.
+func WithHorizontalBars() BarChartOption {
+	return func(opts *BarChartOptions) {
+		opts.Horizontal = true
+	}
+}
+
+// WithValueLabels writes the value of every bar on it, formatted with the
+// printf like format, e.g. "%.1f".
+func WithValueLabels(format string) BarChartOption {
+	return func(opts *BarChartOptions) {
+		opts.LabelFormat = format
+	}
+}
+
+// barChart is the part of a bar chart kept to draw and update it.
+type barChart struct {
+	categories []string
+	names      []string
+	styles     []PointGroupOptions
+	errors     []bool // whether the series have error whiskers
+	options    BarChartOptions
+}
+
+// barColumns is the number of columns written for every series: the
+// position of the tip of the bar, its box, its value and its error.
+const barColumns = 8
+
+// columns computes the boxes of the bars of all the series.
+func (chart *barChart) columns(series []BarSeries) ([][]float64, error) {
+	ncategories := len(chart.categories)
+	nseries := len(series)
+	width := 1 / (float64(nseries) + chart.options.Gap)
+	if chart.options.Mode == BarsStacked {
+		width = 1 / (1 + chart.options.Gap)
+	}
+	positive := make([]float64, ncategories) // tops of the positive stacks
+	negative := make([]float64, ncategories) // bottoms of the negative stacks
+
+	columns := make([][]float64, 0, barColumns*nseries)
+	for k, s := range series {
+		values, err := barValues(s.Values, ncategories, s.Name, "values")
+		if err != nil {
+			return nil, err
+		}
+		errors := make([]float64, ncategories)
+		if s.Errors != nil {
+			errors, err = barValues(s.Errors, ncategories, s.Name, "errors")
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		center := make([]float64, ncategories)
+		low := make([]float64, ncategories)
+		high := make([]float64, ncategories)
+		base := make([]float64, ncategories)
+		tip := make([]float64, ncategories)
+		for i, value := range values {
+			center[i] = float64(i)
+			if chart.options.Mode == BarsClustered {
+				center[i] += -0.5 + width*(chart.options.Gap/2+float64(k)+0.5)
+			} else if value >= 0 {
+				base[i] = positive[i]
+				positive[i] += value
+			} else {
+				base[i] = negative[i]
+				negative[i] += value
+			}
+			low[i], high[i] = center[i]-width/2, center[i]+width/2
+			tip[i] = base[i] + value
+		}
+		if chart.options.Horizontal {
+			columns = append(columns, tip, center, base, tip, low, high, values, errors)
+		} else {
+			columns = append(columns, center, tip, low, high, base, tip, values, errors)
+		}
+	}
+	return columns, nil
+}
+
+// barValues converts the values or the errors of a series.
+func barValues(data any, ncategories int, name, what string) ([]float64, error) {
+	columns, err := castData(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) != 1 || len(columns[0]) != ncategories {
+		return nil, &GnuplotError{err: fmt.Sprintf("the series %s must have one of its %s per category", name, what)}
+	}
+	return columns[0], nil
+}
+
+// AddBarChart adds a bar chart of one or more named series over the
+// categories. The axis of the categories gets their names as tic labels and
+// a range fitting them.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.AddBarChart("requests", []string{"api", "web", "worker"}, []glot.BarSeries{
+//		{Name: "eu", Values: []int{120, 80, 30}, Errors: []int{10, 5, 3}},
+//		{Name: "us", Values: []int{90, 110, 20}, Style: []glot.PointGroupOption{glot.WithColor("orange")}},
+//	}, glot.WithBarMode(glot.BarsStacked), glot.WithValueLabels("%.0f"))
+func (plot *plot) AddBarChart(name string, categories []string, series []BarSeries, opts ...BarChartOption) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	err := plot.checkNewName(name)
+	if err != nil {
+		return err
+	}
+	if plot.dimensions != 2 {
+		return &GnuplotError{err: "bar charts can only be drawn on 2 dimensional plots"}
+	}
+	if len(categories) == 0 || len(series) == 0 {
+		return &GnuplotError{err: "a bar chart needs at least one category and one series"}
+	}
+	options := BarChartOptions{Gap: 1}
+	for _, option := range opts {
+		option(&options)
+	}
+	if options.Gap < 0 || math.IsNaN(options.Gap) || math.IsInf(options.Gap, 0) {
+		return &GnuplotError{err: fmt.Sprintf("invalid gap %v", options.Gap)}
+	}
+	if options.Mode != BarsClustered && options.Mode != BarsStacked {
+		return &GnuplotError{err: fmt.Sprintf("unknown bar mode %d", options.Mode)}
+	}
+
+	chart := &barChart{categories: categories, options: options}
+	err = chart.setSeries(series, plot.dimensions)
+	if err != nil {
+		return err
+	}
+	columns, err := chart.columns(series)
+	if err != nil {
+		return err
+	}
+
+	curve := &pointGroup{name: name, dimensions: 2, data: series, set: true, style: "boxxyerror"}
+	curve.bars = chart
+	curve.columns = columns
+	err = plot.addPointGroup(curve)
+	if err != nil {
+		return err
+	}
+
+	// the categories are set only for a bar chart gnuplot accepted
+	axis := AxisX
+	if options.Horizontal {
+		axis = AxisY
+	}
+	err = plot.set(string(axis)+"tics", fmt.Sprintf("set %stics %s", axis, ticLabels(categories)))
+	if err != nil {
+		return err
+	}
+	err = plot.setRange(axis, Range{Min: At(-0.5), Max: At(float64(len(categories)) - 0.5)})
+	if err != nil {
+		return err
+	}
+	return plot.replot()
+}
+
+// setSeries keeps the names and the styles of the series.
+func (chart *barChart) setSeries(series []BarSeries, dimensions int) error {
+	names := make([]string, len(series))
+	styles := make([]PointGroupOptions, len(series))
+	errors := make([]bool, len(series))
+	for k, s := range series {
+		style := PointGroupOptions{Fill: &Fill{Border: true}}
+		style.apply(s.Style)
+		err := style.check(dimensions)
+		if err != nil {
+			return err
+		}
+		names[k] = s.Name
+		styles[k] = style
+		errors[k] = s.Errors != nil
+	}
+	chart.names, chart.styles, chart.errors = names, styles, errors
+	return nil
+}
+
+// barChartElement returns the part of the plot command drawing the bars,
+// their error whiskers and their labels.
+func (pointGroup *pointGroup) barChartElement() string {
+	chart := pointGroup.bars
+	var elements []string
+	for k, name := range chart.names {
+		// column returns the number of the i-th column of the series
+		column := func(i int) int {
+			return barColumns*k + i
+		}
+		element := fmt.Sprintf("%s using %d:%d:%d:%d:%d:%d", pointGroup.source,
+			column(1), column(2), column(3), column(4), column(5), column(6))
+		style := &chart.styles[k]
+		if style.Axes != "" {
+			element += " axes " + string(style.Axes)
+		}
+		element += " title " + doubleQuote(name) + " with boxxyerror"
+		if clause := style.styleClause(); clause != "" {
+			element += " " + clause
+		}
+		elements = append(elements, element)
+
+		if chart.errors[k] {
+			whiskers := "yerrorbars"
+			if chart.options.Horizontal {
+				whiskers = "xerrorbars"
+			}
+			elements = append(elements, fmt.Sprintf("%s using %d:%d:%d notitle with %s lc rgb 'black' pt 0",
+				pointGroup.source, column(1), column(2), column(8), whiskers))
+		}
+
+		if chart.options.LabelFormat != "" {
+			x, y := fmt.Sprintf("$%d", column(1)), fmt.Sprintf("$%d", column(2))
+			placement := "center offset 0,0.7"
+			if chart.options.Horizontal {
+				placement = "left offset 0.7,0"
+			}
+			if chart.options.Mode == BarsStacked {
+				// in the middle of the bar
+				placement = "center"
+				if chart.options.Horizontal {
+					x = fmt.Sprintf("($%d+$%d)/2", column(3), column(4))
+				} else {
+					y = fmt.Sprintf("($%d+$%d)/2", column(5), column(6))
+				}
+			}
+			elements = append(elements, fmt.Sprintf("%s using (%s):(%s):(sprintf(%s, $%d)) notitle with labels %s",
+				pointGroup.source, x, y, doubleQuote(chart.options.LabelFormat), column(7), placement))
+		}
+	}
+	return strings.Join(elements, ", ")
+}
@@ -1,8 +1,12 @@
 package glot
 
 import (
+	"bytes"
+	"context"
 	"fmt"
-	"strconv"
+	"io"
+	"os"
+	"strings"
 )
 
 // SetTitle sets the title for the plot
@@ -16,7 +20,9 @@ import (
 //	plot.AddPointGroup("Sample 1", "lines", []float64{2, 3, 4, 1})
 //	plot.SetTitle("Test Results")
 func (plot *plot) SetTitle(title string) error {
-	return plot.cmd(fmt.Sprintf("set title \"%s\" ", title))
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.set("title", fmt.Sprintf("set title \"%s\" ", title))
 }
 
 // SetXLabel changes the label for the x-axis
@@ -31,7 +37,9 @@ func (plot *plot) SetTitle(title string) error {
 //	 plot.SetTitle("Test Results")
 //		plot.SetXLabel("X-Axis")
 func (plot *plot) SetXLabel(label string) error {
-	return plot.cmd(fmt.Sprintf("set xlabel '%s'", label))
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.set("xlabel", fmt.Sprintf("set xlabel '%s'", label))
 }
 
 // SetYLabel changes the label for the y-axis
@@ -46,7 +54,9 @@ func (plot *plot) SetXLabel(label string) error {
 //	 plot.SetTitle("Test Results")
 //		plot.SetYLabel("Y-Axis")
 func (plot *plot) SetYLabel(label string) error {
-	return plot.cmd(fmt.Sprintf("set ylabel '%s'", label))
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.set("ylabel", fmt.Sprintf("set ylabel '%s'", label))
 }
 
 // SetZLabel changes the label for the z-axis
@@ -61,11 +71,39 @@ func (plot *plot) SetYLabel(label string) error {
 //	 plot.SetTitle("Test Results")
 //		plot.SetZLabel("Z-Axis")
 func (plot *plot) SetZLabel(label string) error {
-	return plot.cmd(fmt.Sprintf("set zlabel '%s'", label))
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.set("zlabel", fmt.Sprintf("set zlabel '%s'", label))
+}
+
+// SetX2Label changes the label for the secondary x-axis at the top of the plot
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.SetX2Label("X2-Axis")
+func (plot *plot) SetX2Label(label string) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.set("x2label", fmt.Sprintf("set x2label '%s'", label))
+}
+
+// SetY2Label changes the label for the secondary y-axis at the right of the plot
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.SetY2Label("Throughput, req/s")
+func (plot *plot) SetY2Label(label string) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.set("y2label", fmt.Sprintf("set y2label '%s'", label))
 }
 
 func (plot *plot) SetGrid() error {
-	return plot.cmd("set grid")
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.set("grid", "set grid")
 }
 
 // SetLabels Functions helps to set labels for x, y, z axis  simultaneously
@@ -80,14 +118,17 @@ func (plot *plot) SetGrid() error {
 //	 plot.SetTitle("Test Results")
 //		plot.SetLabels("X-axis","Y-Axis","Z-Axis")
 func (plot *plot) SetLabels(labels ...string) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
 	ndims := len(labels)
 	if ndims > 3 || ndims <= 0 {
-		return &gnuplotError{fmt.Sprintf("invalid number of dims '%v'", ndims)}
+		return &GnuplotError{err: fmt.Sprintf("invalid number of dims '%v'", ndims)}
 	}
-	slabelFunc := []func(string) error{plot.SetXLabel, plot.SetYLabel, plot.SetZLabel}
+	axes := []string{"x", "y", "z"}
 
 	for i, label := range labels {
-		err := slabelFunc[i](label)
+		err := plot.set(axes[i]+"label", fmt.Sprintf("set %slabel '%s'", axes[i], label))
 		if err != nil {
 			return err
 		}
@@ -106,11 +147,17 @@ func (plot *plot) SetLabels(labels ...string) error {
 //	 plot.AddPointGroup("Sample 1", "lines", []float64{2, 3, 4, 1})
 //	 plot.SetTitle("Test Results")
 //		plot.SetXrange(-2,2)
+//
+// SetXrange is kept for compatibility and only takes integer bounds; use
+// SetRange(glot.AxisX, r) for float, open-ended or reversed ranges.
 func (plot *plot) SetXrange(start int, end int) error {
-	return plot.cmd(fmt.Sprintf("set xrange [%d:%d]", start, end))
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.setRange(AxisX, Range{Min: At(float64(start)), Max: At(float64(end))})
 }
 
 // SetLogscale changes the label for the x-axis
+// The axis can be any of x, y, z, x2, y2, cb or r.
 //
 // Usage
 //
@@ -122,7 +169,9 @@ func (plot *plot) SetXrange(start int, end int) error {
 //	plot.AddPointGroup("rates", "circle", [][]float64{{2, 4, 8, 16, 32}, {4, 7, 4, 10, 3}})
 //	plot.SetLogscale("x", 2)
 func (plot *plot) SetLogscale(axis string, base int) error {
-	return plot.cmd(fmt.Sprintf("set logscale %s %d", axis, base))
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.set("logscale "+axis, fmt.Sprintf("set logscale %s %d", axis, base))
 }
 
 // SetYrange changes the label for the y-axis
@@ -136,8 +185,13 @@ func (plot *plot) SetLogscale(axis string, base int) error {
 //	 plot.AddPointGroup("Sample 1", "lines", []float64{2, 3, 4, 1})
 //	 plot.SetTitle("Test Results")
 //		plot.SetYrange(-2,2)
+//
+// SetYrange is kept for compatibility and only takes integer bounds; use
+// SetRange(glot.AxisY, r) for float, open-ended or reversed ranges.
 func (plot *plot) SetYrange(start int, end int) error {
-	return plot.cmd(fmt.Sprintf("set yrange [%d:%d]", start, end))
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.setRange(AxisY, Range{Min: At(float64(start)), Max: At(float64(end))})
 }
 
 // SetZrange changes the label for the z-axis
@@ -151,13 +205,46 @@ func (plot *plot) SetYrange(start int, end int) error {
 //	 plot.AddPointGroup("Sample 1", "lines", []float64{2, 3, 4, 1})
 //	 plot.SetTitle("Test Results")
 //		plot.SetZrange(-2,2)
+//
+// SetZrange is kept for compatibility and only takes integer bounds; use
+// SetRange(glot.AxisZ, r) for float, open-ended or reversed ranges.
 func (plot *plot) SetZrange(start int, end int) error {
-	return plot.cmd(fmt.Sprintf("set zrange [%d:%d]", start, end))
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.setRange(AxisZ, Range{Min: At(float64(start)), Max: At(float64(end))})
+}
+
+// SetX2range changes the range for the secondary x-axis
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.SetX2range(glot.Range{Min: glot.At(-2.5), Max: glot.At(2.5)})
+func (plot *plot) SetX2range(r Range) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.setRange(AxisX2, r)
+}
+
+// SetY2range changes the range for the secondary y-axis
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.SetY2range(glot.Range{Min: glot.At(0), Max: glot.At(1000)})
+func (plot *plot) SetY2range(r Range) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.setRange(AxisY2, r)
 }
 
 // SavePlot function is used to save the plot at this point.
 // The plot is dynamic and additional pointgroups can be added and removed and different versions
 // of the same plot can be saved.
+// SavePlot blocks until gnuplot has finished writing the file, so the file
+// can be used as soon as the function returns.
+// The size is given in pixels, except for the terminals documenting
+// another unit (see SetTerminal).
 //
 // Usage
 //
@@ -170,19 +257,153 @@ func (plot *plot) SetZrange(start int, end int) error {
 //		plot.SetZrange(-2,2)
 //	 plot.SavePlot("1.jpeg")
 func (plot *plot) SavePlot(filename string, weight, height int) error {
-	if plot.nPlots == 0 {
-		return &gnuplotError{fmt.Sprintf("This plot has 0 curves and therefore its a redundant plot and it can't be printed.")}
+	return plot.SavePlotContext(plot.ctx, filename, weight, height)
+}
+
+// SavePlotContext is like SavePlot but gives up when ctx is done. In that case
+// the gnuplot process is killed, so the plot can't be used anymore.
+//
+// Usage
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+//	defer cancel()
+//	err := plot.SavePlotContext(ctx, "1.png", 800, 600)
+func (plot *plot) SavePlotContext(ctx context.Context, filename string, weight, height int) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	if len(plot.pointGroup) == 0 {
+		return &GnuplotError{err: fmt.Sprintf("This plot has 0 curves and therefore its a redundant plot and it can't be printed.")}
 	}
-	outputFormat := "set terminal " + string(plot.format) +
-		" size " + strconv.Itoa(weight) + ", " + strconv.Itoa(height)
+	err := plot.cmdContext(ctx, saveScript(plot.terminal.command(weight, height), filename, "replot"))
+	if err != nil {
+		return err
+	}
+	return checkSavedFile(filename)
+}
+
+// saveScript returns the commands drawing a plot into a file with the given
+// terminal. The commands are sent as a single batch so the previous terminal
+// is restored even if drawing fails. Resetting the output closes the file,
+// which makes gnuplot flush it to disk.
+func saveScript(terminal, filename, draw string) string {
+	return strings.Join([]string{
+		"set terminal push",
+		terminal,
+		"set output " + quote(filename),
+		draw,
+		"set output",
+		"set terminal pop",
+	}, "\n")
+}
 
-	_ = plot.cmd(outputFormat)
-	outputFileCommand := "set output " + "'" + filename + "'"
-	_ = plot.cmd(outputFileCommand)
-	_ = plot.cmd("replot  ")
+// checkSavedFile makes sure gnuplot wrote the file.
+func checkSavedFile(filename string) error {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		return &GnuplotError{err: fmt.Sprintf("gnuplot produced an empty file %s", filename)}
+	}
 	return nil
 }
 
+// Render writes the plot encoded in the given format to w.
+// The image is streamed from gnuplot directly, no file is created.
+// The terminal options set by SetTerminal are used if they match the format.
+//
+// Usage
+//
+//	 dimensions := 2
+//	 persist := false
+//	 plot, _ := glot.NewPlot(dimensions, persist)
+//	 plot.AddPointGroup("Sample 1", "lines", []float64{2, 3, 4, 1})
+//		plot.Render(responseWriter, glot.FormatPng, 800, 600)
+func (plot *plot) Render(w io.Writer, format Format, width, height int) error {
+	return plot.RenderContext(plot.ctx, w, format, width, height)
+}
+
+// RenderContext is like Render but gives up when ctx is done. In that case
+// the gnuplot process is killed, so the plot can't be used anymore.
+func (plot *plot) RenderContext(ctx context.Context, w io.Writer, format Format, width, height int) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	if len(plot.pointGroup) == 0 {
+		return &GnuplotError{err: fmt.Sprintf("This plot has 0 curves and therefore its a redundant plot and it can't be printed.")}
+	}
+
+	begin, end := plot.proc.stdout.expect()
+	err := plot.cmdContext(ctx, renderScript(plot.terminalFor(format).command(width, height), begin, end, "replot"))
+	if err != nil {
+		plot.proc.stdout.cancel()
+		return err
+	}
+
+	frame, err := plot.proc.frameContext(ctx, begin, end)
+	if err != nil {
+		if plot.contextErr(ctx) != nil {
+			plot.tmpFiles.remove()
+		}
+		return err
+	}
+	_, err = w.Write(frame)
+	return err
+}
+
+// renderScript returns the commands drawing a plot to stdout with the given
+// terminal. The plot is printed between two markers which let us find where
+// it starts and ends. Resetting the output makes gnuplot finish the image
+// before the closing marker is printed.
+func renderScript(terminal, begin, end, draw string) string {
+	return strings.Join([]string{
+		"set terminal push",
+		terminal,
+		"set output",
+		"set print '-'",
+		"print " + quote(begin),
+		draw,
+		"set output",
+		"print " + quote(end),
+		"set print",
+		"set terminal pop",
+	}, "\n")
+}
+
+// Bytes returns the plot encoded in the given format.
+//
+// Usage
+//
+//	 dimensions := 2
+//	 persist := false
+//	 plot, _ := glot.NewPlot(dimensions, persist)
+//	 plot.AddPointGroup("Sample 1", "lines", []float64{2, 3, 4, 1})
+//		png, _ := plot.Bytes(glot.FormatPng, 800, 600)
+func (plot *plot) Bytes(format Format, width, height int) ([]byte, error) {
+	var buf bytes.Buffer
+	err := plot.Render(&buf, format, width, height)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// terminalFor returns the terminal of the plot if it produces the format,
+// or the terminal producing the format with the default options otherwise.
+func (plot *plot) terminalFor(format Format) Terminal {
+	return matchTerminal(plot.terminal, format)
+}
+
+// matchTerminal returns terminal if it produces the format, or the terminal
+// producing the format with the default options otherwise.
+func matchTerminal(terminal Terminal, format Format) Terminal {
+	if terminal.Format() == format {
+		return terminal
+	}
+	return defaultTerminal(format)
+}
+
 // SetFormat function is used to save the plot at this point.
 // The plot is dynamic and additional pointgroups can be added and removed and different versions
 // of the same plot can be saved.
@@ -200,10 +421,76 @@ func (plot *plot) SavePlot(filename string, weight, height int) error {
 //
 // NOTE: png is default format for saving files.
 func (plot *plot) SetFormat(newformat Format) error {
-	plot.format = newformat
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	plot.terminal = defaultTerminal(newformat)
+	return nil
+}
+
+// SetTerminal sets the output format together with the terminal options
+// used when the plot is saved.
+//
+// Usage
+//
+//	plot.SetTerminal(glot.PdfCairoOptions{
+//		Font:  glot.Font{Name: "Helvetica", Size: 10},
+//		Units: glot.UnitInches,
+//	})
+//	plot.SavePlot("1.pdf", 5, 3)
+func (plot *plot) SetTerminal(terminal Terminal) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	if terminal == nil {
+		return &GnuplotError{err: "terminal must not be nil"}
+	}
+	plot.terminal = terminal
+	return nil
+}
+
+// SetDataTransport selects how the data of the point groups added afterwards
+// is sent to gnuplot. By default every point group is written to a temporary
+// text file; TransportDataBlock sends the data over the gnuplot stdin instead
+// and TransportBinary writes binary files.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.SetDataTransport(glot.TransportDataBlock)
+//	plot.AddPointGroup("Sample 1", "lines", []float64{2, 3, 4, 1})
+func (plot *plot) SetDataTransport(transport DataTransport) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	if transport < TransportFile || transport > TransportBinary {
+		return &GnuplotError{err: fmt.Sprintf("unknown data transport %d", transport)}
+	}
+	plot.transport = transport
+	return nil
+}
+
+// SetBinaryThreshold makes point groups with at least the given number of
+// points be written to binary files whatever the data transport is.
+// A threshold of 0 disables the automatic switch.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.SetBinaryThreshold(100000)
+func (plot *plot) SetBinaryThreshold(points int) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	if points < 0 {
+		return &GnuplotError{err: fmt.Sprintf("invalid binary threshold %d", points)}
+	}
+	plot.binaryThreshold = points
 	return nil
 }
 
 func (plot *plot) SetKeyOutside() error {
-	return plot.cmd("set key outside")
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.set("key", "set key outside")
 }
@@ -1,27 +1,57 @@
 package glot
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"regexp"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 var gGnuplotCmd string
 var gGnuplotPrefix = "go-gnuplot-"
+var gSyncPrefix = "go-gnuplot-sync-"
+var gFramePrefix = "go-gnuplot-frame-"
+var gBlockPrefix = "go_gnuplot_data_"
+
+// gnuplot reports diagnostics as `line <n>: <message>`, optionally prefixed
+// with the quoted name of the file being read.
+var gErrorPattern = regexp.MustCompile(`^(?:"[^"]*",? )?line (\d+): (.*)$`)
 
 const defaultStyle = "points" // The default style for a curve
-const plotCommand = "replot"  // The default style for a curve
 
 // A map between os files and file names
 type tempFilesDb map[string]*os.File
 
+// remove closes and deletes all the files of the database.
+func (db tempFilesDb) remove() {
+	for name, f := range db {
+		f.Close()
+		os.Remove(name)
+		delete(db, name)
+	}
+}
+
+// initGnuplot initializes the package once for all the plots, however many
+// are created concurrently.
+var initGnuplot = sync.OnceValue(initialize)
+
 // Function to intialize the package and check for GNU plot installation
 // This raises an error if GNU plot is not installed
 func initialize() error {
 	var err error
 
+	if gGnuplotCmd != "" {
+		// set by SetCustomPathToGNUPlot
+		return nil
+	}
 	gnuplotExecutableName := "gnuplot"
 
 	if runtime.GOOS == "windows" {
@@ -36,80 +66,289 @@ func initialize() error {
 	return nil
 }
 
-type gnuplotError struct {
-	err string
+// GnuplotError is the error returned by the plots. Errors reported by gnuplot
+// carry the command gnuplot rejected, its diagnostic and the line it was
+// reported at; errors found by the library itself only have a description.
+//
+// Usage
+//
+//	err := plot.SetXrange(-10, 10)
+//	var gerr *glot.GnuplotError
+//	if errors.As(err, &gerr) && gerr.Command() != "" {
+//		log.Printf("gnuplot rejected %q: %s", gerr.Command(), gerr.Message())
+//	}
+type GnuplotError struct {
+	err     string
+	command string // command that caused the error, empty for library errors
+	message string // diagnostic reported by gnuplot
+	line    int    // line number reported by gnuplot
 }
 
-func (e *gnuplotError) Error() string {
+func (e *GnuplotError) Error() string {
 	return e.err
 }
 
+// Command returns the command gnuplot rejected, or an empty string when the
+// error was found by the library.
+func (e *GnuplotError) Command() string {
+	return e.command
+}
+
+// Message returns the diagnostic reported by gnuplot.
+func (e *GnuplotError) Message() string {
+	return e.message
+}
+
+// Line returns the line number gnuplot reported the error at, 0 if unknown.
+func (e *GnuplotError) Line() int {
+	return e.line
+}
+
+// parseGnuplotError checks if a line of the gnuplot diagnostic output
+// reports an error. Warnings are not treated as errors.
+func parseGnuplotError(command string, output string) *GnuplotError {
+	match := gErrorPattern.FindStringSubmatch(strings.TrimSpace(output))
+	if match == nil {
+		return nil
+	}
+	message := match[2]
+	if strings.HasPrefix(message, "warning:") {
+		return nil
+	}
+	line, _ := strconv.Atoi(match[1])
+	return &GnuplotError{
+		err:     fmt.Sprintf("gnuplot error at line %d: %s (command: %q)", line, message, command),
+		command: command,
+		message: message,
+		line:    line,
+	}
+}
+
 // plotterProcess is the type for handling gnu commands.
 type plotterProcess struct {
 	handle *exec.Cmd
 	stdin  io.WriteCloser
+	stderr *bufio.Reader
+	pipe   io.Closer // read end of the stderr pipe
+	stdout *outputStream
+	nSyncs int // number of synchronization markers sent so far
+}
+
+// outputStream collects everything gnuplot writes to stdout. Rendered plots
+// are framed by markers printed before and after them; data outside of an
+// expected frame is dropped.
+type outputStream struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pipe    io.ReadCloser
+	data    []byte
+	pending bool  // a frame is expected, keep the incoming data
+	nFrames int   // number of frames requested so far
+	err     error // error that stopped reading
+}
+
+func newOutputStream(pipe io.ReadCloser) *outputStream {
+	stream := &outputStream{pipe: pipe}
+	stream.cond = sync.NewCond(&stream.mu)
+	go stream.read()
+	return stream
+}
+
+func (stream *outputStream) read() {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := stream.pipe.Read(buf)
+		stream.mu.Lock()
+		if stream.pending {
+			stream.data = append(stream.data, buf[:n]...)
+		}
+		if err != nil {
+			stream.err = err
+		}
+		stream.cond.Broadcast()
+		stream.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// expect announces a new frame and returns the markers that must be printed
+// to stdout right before and right after it.
+func (stream *outputStream) expect() (begin, end string) {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	stream.nFrames++
+	stream.pending = true
+	stream.data = nil
+	prefix := gFramePrefix + strconv.Itoa(stream.nFrames)
+	return prefix + "-begin", prefix + "-end"
+}
+
+// cancel drops a frame announced by expect which will never be printed.
+func (stream *outputStream) cancel() {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	stream.pending = false
+	stream.data = nil
+}
+
+// frame waits until the frame delimited by the given markers is complete and
+// returns its content.
+func (stream *outputStream) frame(begin, end string) ([]byte, error) {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	// print terminates the markers with a new line
+	beginMarker := []byte(begin + "\n")
+	endMarker := []byte(end + "\n")
+	from := -1   // start of the frame, once the begin marker is found
+	scanned := 0 // length of the data searched so far, a marker may straddle it
+	for {
+		if from < 0 {
+			start := max(0, scanned-len(beginMarker)+1)
+			i := bytes.Index(stream.data[start:], beginMarker)
+			if i >= 0 {
+				from = start + i + len(beginMarker)
+				scanned = from
+			}
+		}
+		if from >= 0 {
+			start := max(from, scanned-len(endMarker)+1)
+			to := bytes.Index(stream.data[start:], endMarker)
+			if to >= 0 {
+				frame := stream.data[from : start+to]
+				stream.pending = false
+				stream.data = nil
+				return frame, nil
+			}
+		}
+		scanned = len(stream.data)
+		if stream.err != nil {
+			stream.pending = false
+			stream.data = nil
+			return nil, &GnuplotError{err: fmt.Sprintf("gnuplot output closed before the end of the plot: %v", stream.err)}
+		}
+		stream.cond.Wait()
+	}
 }
 
 // NewPlotterProc function makes the plotterProcess struct
-func newPlotterProc(persist bool) (*plotterProcess, error) {
+func newPlotterProc(ctx context.Context, persist bool) (*plotterProcess, error) {
 	procArgs := []string{}
 	if persist {
 		procArgs = append(procArgs, "-persist")
 	}
-	cmd := exec.Command(gGnuplotCmd, procArgs...)
+	cmd := exec.CommandContext(ctx, gGnuplotCmd, procArgs...)
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
 		return nil, err
 	}
 
-	// to debug uncomment it
-	// stderr, err := cmd.StderrPipe()
-	// if err != nil {
-	// 	return nil, err
-	// }
-	// stdout, err := cmd.StdoutPipe()
-	// if err != nil {
-	// 	return nil, err
-	// }
-	// stderrReader := bufio.NewReader(stderr)
-	// stdoutReader := bufio.NewReader(stdout)
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
 
 	err = cmd.Start()
 	if err != nil {
 		return nil, err
 	}
 
-	// go func() {
-	// 	for {
-	// 		line, _, err := stderrReader.ReadLine()
-	// 		// line, _, err := bufio.NewReader(stderr).ReadLine()
-	// 		fmt.Println("err>" + string(line))
-	// 		if err != nil {
-	// 			fmt.Println(err)
-	// 			return
-	// 		}
-	// 	}
-	// }()
-	//
-	// go func() {
-	// 	for {
-	// 		line, _, err := stdoutReader.ReadLine()
-	// 		fmt.Println("out>" + string(line))
-	// 		if err != nil {
-	// 			fmt.Println(err)
-	// 			return
-	// 		}
-	// 	}
-	// }()
-
 	return &plotterProcess{
 		handle: cmd,
 		stdin:  stdin,
+		stderr: bufio.NewReader(stderr),
+		pipe:   stderr,
+		stdout: newOutputStream(stdout),
 	}, nil
 }
 
-// Cmd sends a command to the gnuplot subprocess and returns an error
-// if something bad happened in the gnuplot process.
+// kill terminates the gnuplot subprocess. The stderr pipe is closed as well,
+// so a pending exec returns even if a child of gnuplot keeps the pipe open.
+func (proc *plotterProcess) kill() {
+	if proc.handle.Process != nil {
+		proc.handle.Process.Kill()
+	}
+	proc.pipe.Close()
+	proc.stdout.pipe.Close()
+}
+
+// exec sends a command to the gnuplot subprocess followed by a synchronization
+// marker and waits until gnuplot prints the marker back. Everything gnuplot
+// writes to stderr in between is returned as the command output; if it
+// contains an error diagnostic, a *GnuplotError is returned as well.
+func (proc *plotterProcess) exec(command string) ([]string, error) {
+	proc.nSyncs++
+	marker := gSyncPrefix + strconv.Itoa(proc.nSyncs)
+	_, err := io.WriteString(proc.stdin, command+"\nprint \""+marker+"\"\n")
+	if err != nil {
+		return nil, err
+	}
+
+	var output []string
+	var cmdErr *GnuplotError
+	for {
+		line, err := proc.stderr.ReadString('\n')
+		if err != nil {
+			return output, &GnuplotError{
+				err:     fmt.Sprintf("gnuplot terminated while executing %q: %v", command, err),
+				command: command,
+			}
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == marker {
+			break
+		}
+		if cmdErr == nil {
+			cmdErr = parseGnuplotError(command, line)
+		}
+		output = append(output, line)
+	}
+	if cmdErr != nil {
+		return output, cmdErr
+	}
+	return output, nil
+}
+
+// execContext is like exec but kills the subprocess when ctx is done, since
+// gnuplot can't be interrupted in the middle of a command.
+func (proc *plotterProcess) execContext(ctx context.Context, command string) (output []string, err error) {
+	if ctx.Done() == nil {
+		return proc.exec(command)
+	}
+	done := make(chan struct{})
+	go func() {
+		output, err = proc.exec(command)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		proc.kill()
+		<-done
+	}
+	return output, err
+}
+
+// frameContext waits for a frame like outputStream.frame but kills the
+// subprocess when ctx is done, which closes the output and ends the wait.
+func (proc *plotterProcess) frameContext(ctx context.Context, begin, end string) ([]byte, error) {
+	stop := context.AfterFunc(ctx, proc.kill)
+	defer stop()
+	frame, err := proc.stdout.frame(begin, end)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return frame, err
+}
+
+// Cmd sends a command to the gnuplot subprocess and waits for gnuplot to
+// process it. It returns an error if gnuplot reported a problem with
+// the command or if something bad happened in the gnuplot process.
 // ex:
 //
 //	fname := "foo.dat"
@@ -120,11 +359,63 @@ func newPlotterProc(persist bool) (*plotterProcess, error) {
 //
 // func (plot *plot) cmd(command string) error {
 func (plot *plot) cmd(command string) error {
-	_, err := io.WriteString(plot.proc.stdin, command+"\n")
+	return plot.cmdContext(plot.ctx, command)
+}
+
+// set runs a command changing a setting of the plot and records it under
+// key, replacing the command recorded before under the same key. The
+// recorded settings are replayed when the plot is drawn in a Multiplot.
+func (plot *plot) set(key, command string) error {
+	err := plot.cmd(command)
+	if err != nil {
+		return err
+	}
+	for i := range plot.settings {
+		if plot.settings[i].key == key {
+			plot.settings[i].command = command
+			return nil
+		}
+	}
+	plot.settings = append(plot.settings, setting{key: key, command: command})
+	return nil
+}
 
+// cmdContext is like cmd but gives up when ctx is done.
+func (plot *plot) cmdContext(ctx context.Context, command string) error {
+	_, err := plot.execContext(ctx, command)
 	return err
 }
 
+// execContext runs a command and returns its output. gnuplot can't be
+// interrupted in the middle of a command, so when ctx is done the subprocess
+// is killed and the temporary files of the plot are removed.
+func (plot *plot) execContext(ctx context.Context, command string) ([]string, error) {
+	err := plot.contextErr(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := plot.proc.execContext(ctx, command)
+	if err != nil {
+		// The process also dies when the context of the plot is done.
+		ctxErr := plot.contextErr(ctx)
+		if ctxErr != nil {
+			plot.tmpFiles.remove()
+			return output, ctxErr
+		}
+	}
+	return output, err
+}
+
+// contextErr reports whether either ctx or the context of the plot is done.
+func (plot *plot) contextErr(ctx context.Context) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+	return plot.ctx.Err()
+}
+
 // Close makes sure all resources used by the gnuplot subprocess are reclaimed.
 // This method is typically called when the Plotter instance is not needed
 // anymore. That's usually done via a defer statement:
@@ -133,31 +424,44 @@ func (plot *plot) cmd(command string) error {
 //	if err != nil { /* handle error */ }
 //	defer p.Close()
 func (plot *plot) Close() (err error) {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	if plot.stopCleanup != nil {
+		plot.stopCleanup()
+	}
 	if plot.proc != nil && plot.proc.handle != nil {
 		plot.proc.stdin.Close()
 		err = plot.proc.handle.Wait()
 	}
-	plot.resetPlot()
+	// the data blocks are gone together with the process
+	plot.tmpFiles.remove()
+	plot.pointGroup = make(map[string]*pointGroup)
+	plot.order = nil
 	return err
 }
 
-func (plot *plot) cleanplot() (err error) {
-	plot.tmpFiles = make(tempFilesDb)
-	plot.nPlots = 0
-	return err
+// quote returns s as a single-quoted gnuplot string.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
 }
 
-// ResetPlot is used to reset the whole plot.
-// This removes all the PointGroup's from the plot and makes it new.
-// Usage
-//
-//	plot.ResetPlot()
-func (plot *plot) resetPlot() (err error) {
-	plot.cleanplot()
-	plot.pointGroup = make(map[string]*pointGroup) // Adding a mapping between a curve name and a curve
-	return err
+// formatFloat formats a number for gnuplot commands.
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
 }
 
+// doubleQuote returns s as a double-quoted gnuplot string, in which gnuplot
+// interprets escape sequences such as new lines.
+func doubleQuote(s string) string {
+	return `"` + gDoubleQuoteReplacer.Replace(s) + `"`
+}
+
+var gDoubleQuoteReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+
+// SetCustomPathToGNUPlot makes the plots run the gnuplot executable at path
+// instead of the one found in PATH. It must be called before the first plot
+// is created.
 func SetCustomPathToGNUPlot(path string) {
 	gGnuplotCmd = path
 }
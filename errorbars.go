@@ -0,0 +1,208 @@
+package glot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// gStyleColumns holds the numbers of columns accepted by the styles which
+// need more than the coordinates of the points on 2 dimensional plots.
+var gStyleColumns = map[Style][]int{
+	StyleErrorBars:    {3, 4},    // x y ydelta, or x y ylow yhigh
+	StyleYErrorBars:   {3, 4},    // x y ydelta, or x y ylow yhigh
+	StyleYErrorLines:  {3, 4},    // x y ydelta, or x y ylow yhigh
+	StyleXErrorBars:   {3, 4},    // x y xdelta, or x y xlow xhigh
+	StyleXErrorLines:  {3, 4},    // x y xdelta, or x y xlow xhigh
+	StyleXYErrorBars:  {4, 6},    // x y xdelta ydelta, or x y xlow xhigh ylow yhigh
+	StyleXYErrorLines: {4, 6},    // x y xdelta ydelta, or x y xlow xhigh ylow yhigh
+	StyleBoxErrorBars: {3, 4},    // x y ydelta, or x y ydelta xdelta
+	StyleCandlesticks: {5, 6},    // x open low high close, and the width of the box
+	StyleFinanceBars:  {5},       // x open low high close
+	StyleFilledCurves: {1, 2, 3}, // y or x y filled to the axis, or x ylow yhigh
+}
+
+// formatCounts returns the numbers of columns for an error message.
+func formatCounts(counts []int) string {
+	words := make([]string, len(counts))
+	for i, count := range counts {
+		words[i] = strconv.Itoa(count)
+	}
+	return strings.Join(words, " or ")
+}
+
+// columnData is data knowing its own columns, such as the error bars.
+type columnData interface {
+	columns() ([][]float64, error)
+	defaultStyle() Style // style used when AddPointGroup is given no style
+}
+
+// checkLengths makes sure the named columns have the same length and returns
+// them. The columns after the required ones are optional and left out when
+// they are nil.
+func checkLengths(names []string, required int, columns ...[]float64) ([][]float64, error) {
+	var result [][]float64
+	for i, column := range columns {
+		if column == nil && i >= required {
+			continue
+		}
+		if len(column) != len(columns[0]) {
+			return nil, &GnuplotError{err: fmt.Sprintf("%s has %d values instead of %d", names[i], len(column), len(columns[0]))}
+		}
+		result = append(result, column)
+	}
+	return result, nil
+}
+
+// checkNonNegative makes sure the deltas of the error bars are not negative.
+func checkNonNegative(name string, column []float64) error {
+	for i, value := range column {
+		if value < 0 {
+			return &GnuplotError{err: fmt.Sprintf("%s[%d] is negative: %v", name, i, value)}
+		}
+	}
+	return nil
+}
+
+// YErrorBars are points with an error along y, drawn by StyleYErrorBars by
+// default. Every point is drawn with a bar from Y-YDelta to Y+YDelta.
+//
+// Usage
+//
+//	plot.AddPointGroup("latency", "", glot.YErrorBars{
+//		X:      []float64{1, 2, 3},
+//		Y:      []float64{12, 15, 11},
+//		YDelta: []float64{1, 2.5, 0.5},
+//	})
+type YErrorBars struct {
+	X, Y, YDelta []float64
+}
+
+func (bars YErrorBars) columns() ([][]float64, error) {
+	err := checkNonNegative("YDelta", bars.YDelta)
+	if err != nil {
+		return nil, err
+	}
+	return checkLengths([]string{"X", "Y", "YDelta"}, 3, bars.X, bars.Y, bars.YDelta)
+}
+
+func (bars YErrorBars) defaultStyle() Style {
+	return StyleYErrorBars
+}
+
+// XErrorBars are points with an error along x, drawn by StyleXErrorBars by
+// default.
+type XErrorBars struct {
+	X, Y, XDelta []float64
+}
+
+func (bars XErrorBars) columns() ([][]float64, error) {
+	err := checkNonNegative("XDelta", bars.XDelta)
+	if err != nil {
+		return nil, err
+	}
+	return checkLengths([]string{"X", "Y", "XDelta"}, 3, bars.X, bars.Y, bars.XDelta)
+}
+
+func (bars XErrorBars) defaultStyle() Style {
+	return StyleXErrorBars
+}
+
+// XYErrorBars are points with errors along x and y, drawn by StyleXYErrorBars
+// by default.
+type XYErrorBars struct {
+	X, Y, XDelta, YDelta []float64
+}
+
+func (bars XYErrorBars) columns() ([][]float64, error) {
+	err := checkNonNegative("XDelta", bars.XDelta)
+	if err != nil {
+		return nil, err
+	}
+	err = checkNonNegative("YDelta", bars.YDelta)
+	if err != nil {
+		return nil, err
+	}
+	return checkLengths([]string{"X", "Y", "XDelta", "YDelta"}, 4, bars.X, bars.Y, bars.XDelta, bars.YDelta)
+}
+
+func (bars XYErrorBars) defaultStyle() Style {
+	return StyleXYErrorBars
+}
+
+// BoxErrorBars are boxes of height Y with an error along y, drawn by
+// StyleBoxErrorBars by default. The boxes have the width set by the plot
+// unless Width is given.
+type BoxErrorBars struct {
+	X, Y, YDelta []float64
+	Width        []float64 // width of every box, optional
+}
+
+func (bars BoxErrorBars) columns() ([][]float64, error) {
+	err := checkNonNegative("YDelta", bars.YDelta)
+	if err != nil {
+		return nil, err
+	}
+	// the width is read as the delta of x, so half of it
+	var half []float64
+	if bars.Width != nil {
+		half = make([]float64, len(bars.Width))
+		for i, width := range bars.Width {
+			half[i] = width / 2
+		}
+	}
+	return checkLengths([]string{"X", "Y", "YDelta", "Width"}, 3, bars.X, bars.Y, bars.YDelta, half)
+}
+
+func (bars BoxErrorBars) defaultStyle() Style {
+	return StyleBoxErrorBars
+}
+
+// Candlesticks are financial data drawn by StyleCandlesticks by default: a
+// box from Open to Close with whiskers from Low to High.
+type Candlesticks struct {
+	X, Open, Low, High, Close []float64
+	Width                     []float64 // width of every box, optional
+}
+
+func (candles Candlesticks) columns() ([][]float64, error) {
+	err := checkRange(candles.Low, candles.High)
+	if err != nil {
+		return nil, err
+	}
+	return checkLengths([]string{"X", "Open", "Low", "High", "Close", "Width"}, 5,
+		candles.X, candles.Open, candles.Low, candles.High, candles.Close, candles.Width)
+}
+
+func (candles Candlesticks) defaultStyle() Style {
+	return StyleCandlesticks
+}
+
+// FinanceBars are financial data drawn by StyleFinanceBars by default: a
+// vertical line from Low to High with ticks at Open and Close.
+type FinanceBars struct {
+	X, Open, Low, High, Close []float64
+}
+
+func (bars FinanceBars) columns() ([][]float64, error) {
+	err := checkRange(bars.Low, bars.High)
+	if err != nil {
+		return nil, err
+	}
+	return checkLengths([]string{"X", "Open", "Low", "High", "Close"}, 5,
+		bars.X, bars.Open, bars.Low, bars.High, bars.Close)
+}
+
+func (bars FinanceBars) defaultStyle() Style {
+	return StyleFinanceBars
+}
+
+// checkRange makes sure no low value is above the high value.
+func checkRange(low, high []float64) error {
+	for i := range min(len(low), len(high)) {
+		if low[i] > high[i] {
+			return &GnuplotError{err: fmt.Sprintf("Low[%d] %v is above High[%d] %v", i, low[i], i, high[i])}
+		}
+	}
+	return nil
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"math"
-	"time"
 
 	"github.com/Skrip42/glot"
 )
@@ -37,12 +36,21 @@ func main() {
 	}
 
 	for i, style := range styles {
-		points := make([][]float64, 2)
-		points[0] = make([]float64, 100)
-		points[1] = make([]float64, 100)
+		xs := make([]float64, 100)
+		ys := make([]float64, 100)
+		deltas := make([]float64, 100)
 		for x := range 100 {
-			points[0][x] = float64(x)
-			points[1][x] = (math.Pow(float64(x), 2) / 10) * float64(i)
+			xs[x] = float64(x)
+			ys[x] = (math.Pow(float64(x), 2) / 10) * float64(i)
+			deltas[x] = ys[x] / 10
+		}
+		// the error bar styles need the size of the errors too
+		var points any = [][]float64{xs, ys}
+		switch style {
+		case glot.StyleErrorBars:
+			points = glot.YErrorBars{X: xs, Y: ys, YDelta: deltas}
+		case glot.StyleBoxErrorBars:
+			points = glot.BoxErrorBars{X: xs, Y: ys, YDelta: deltas}
 		}
 		err := plot.AddPointGroup(string(style), style, points)
 		if err != nil {
@@ -53,6 +61,4 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-
-	time.Sleep(time.Second)
 }
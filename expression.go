@@ -0,0 +1,103 @@
+package glot
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+var (
+	gIdentifier  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	gFunctionDef = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\(\s*[A-Za-z_][A-Za-z0-9_]*(\s*,\s*[A-Za-z_][A-Za-z0-9_]*)*\s*\)$`)
+)
+
+// AddExpression adds a curve drawn by gnuplot from an expression of x, or of
+// x and y on 3 dimensional plots, such as "sin(x)*exp(-x/10)". The expression
+// can use the functions and the variables defined by DefineFunction and
+// SetVariable, and it is drawn together with the point groups holding data.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.AddPointGroup("measures", glot.StylePoints, [][]float64{xs, ys})
+//	plot.SetVariable("a", 1.5)
+//	plot.SetVariable("b", 2)
+//	plot.DefineFunction("f(x)", "a*x+b")
+//	plot.AddExpression("model", "f(x)", glot.WithColor("red"))
+func (plot *plot) AddExpression(name, expr string, opts ...PointGroupOption) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.addExpression(name, expr, opts)
+}
+
+func (plot *plot) addExpression(name, expr string, opts []PointGroupOption) error {
+	err := plot.checkNewName(name)
+	if err != nil {
+		return err
+	}
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return &GnuplotError{err: "the expression must not be empty"}
+	}
+	curve := &pointGroup{name: name, dimensions: plot.dimensions, data: expr, set: true, style: string(StyleLines)}
+	curve.options.apply(opts)
+	err = curve.options.check(plot.dimensions)
+	if err != nil {
+		return err
+	}
+	curve.expression = expr
+	return plot.addPointGroup(curve)
+}
+
+// DefineFunction defines a gnuplot function which can be used by the
+// expressions of the plot. Defining a function with the same name again
+// replaces it.
+//
+// Usage
+//
+//	plot.DefineFunction("f(x)", "a*x+b")
+//	plot.DefineFunction("gauss(x, s)", "exp(-x**2/(2*s**2))")
+func (plot *plot) DefineFunction(signature, body string) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	match := gFunctionDef.FindStringSubmatch(strings.ReplaceAll(signature, " ", ""))
+	if match == nil {
+		return &GnuplotError{err: fmt.Sprintf("invalid function signature '%s', expected a name followed by parameters such as f(x)", signature)}
+	}
+	if strings.TrimSpace(body) == "" {
+		return &GnuplotError{err: fmt.Sprintf("the function %s must have a body", signature)}
+	}
+	return plot.set("function "+match[1], match[0]+" = "+body)
+}
+
+// SetVariable sets a gnuplot variable which can be used by the expressions
+// and the functions of the plot. The change shows when the plot is drawn
+// again, for example by adding a curve or saving the plot.
+//
+// Usage
+//
+//	plot.SetVariable("a", 1.5)
+func (plot *plot) SetVariable(name string, value float64) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	if !gIdentifier.MatchString(name) {
+		return &GnuplotError{err: fmt.Sprintf("invalid variable name '%s'", name)}
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return &GnuplotError{err: fmt.Sprintf("invalid value %v of the variable %s", value, name)}
+	}
+	return plot.set("variable "+name, name+" = "+formatReal(value))
+}
+
+// formatReal formats the value so gnuplot reads it as a real number, since
+// the arithmetic of integers truncates the divisions.
+func formatReal(value float64) string {
+	literal := formatFloat(value)
+	if !strings.ContainsAny(literal, ".e") {
+		literal += ".0"
+	}
+	return literal
+}
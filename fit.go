@@ -0,0 +1,204 @@
+package glot
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// gFitPrefix starts the line gnuplot prints the results of a fit on.
+const gFitPrefix = "go-gnuplot-fit"
+
+// FitResult holds the results of a fit.
+type FitResult struct {
+	Params           map[string]float64 // fitted values of the parameters
+	Errors           map[string]float64 // asymptotic standard errors of the parameters
+	ReducedChiSquare float64            // sum of the squared residuals divided by the degrees of freedom, NaN without degrees of freedom
+	DegreesOfFreedom int                // number of points minus the number of parameters
+}
+
+// FitOptions describes what is done with the fitted model.
+type FitOptions struct {
+	Curve string             // name of the curve of the fitted model added to the plot, no curve if empty
+	Style []PointGroupOption // options the curve is drawn with
+}
+
+// FitOption changes the options of a fit.
+type FitOption func(*FitOptions)
+
+// WithFitCurve adds the fitted model to the plot as a curve of the given name.
+func WithFitCurve(name string, opts ...PointGroupOption) FitOption {
+	return func(options *FitOptions) {
+		options.Curve = name
+		options.Style = append(options.Style, opts...)
+	}
+}
+
+// fitUsing returns the columns of the point group gnuplot fits the model to.
+func (plot *plot) fitUsing(pointGroup *pointGroup) (string, error) {
+	if pointGroup.expression != "" || pointGroup.heatmap != nil || pointGroup.bars != nil {
+		return "", &GnuplotError{err: fmt.Sprintf("a model can't be fitted to the curve %s", pointGroup.name)}
+	}
+	ncolumns := len(pointGroup.columns)
+	switch {
+	case plot.dimensions == 3 && ncolumns >= 3:
+		// a fourth column of unit errors tells gnuplot z depends on x and y
+		return "1:2:3:(1)", nil
+	case plot.dimensions == 3:
+		return "", &GnuplotError{err: fmt.Sprintf("the curve %s needs x, y and z columns to fit a model of x and y", pointGroup.name)}
+	case ncolumns == 1:
+		// the values are drawn against their index
+		return "0:1", nil
+	case pointGroup.timeColumn:
+		return "($1):2", nil
+	default:
+		return "1:2", nil
+	}
+}
+
+// Fit fits the model expr to the data of a point group with gnuplot and
+// returns the fitted parameters. The model is an expression of x, or of x
+// and y on 3 dimensional plots, and the params are its parameters with their
+// initial values. The fitted values are kept in gnuplot variables of the same
+// names, so expressions and functions of the plot can use them.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.AddPointGroup("timings", glot.StylePoints, [][]float64{sizes, durations})
+//	result, err := plot.Fit("timings", "a*x+b", map[string]float64{"a": 1, "b": 0},
+//		glot.WithFitCurve("linear model", glot.WithColor("red")))
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Printf("slope %g ± %g\n", result.Params["a"], result.Errors["a"])
+func (plot *plot) Fit(groupName, expr string, params map[string]float64, opts ...FitOption) (FitResult, error) {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	pointGroup, exists := plot.pointGroup[groupName]
+	if !exists {
+		return FitResult{}, &GnuplotError{err: fmt.Sprintf("A curve with name %s does not exist.", groupName)}
+	}
+	using, err := plot.fitUsing(pointGroup)
+	if err != nil {
+		return FitResult{}, err
+	}
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return FitResult{}, &GnuplotError{err: "the model must not be empty"}
+	}
+	if len(params) == 0 {
+		return FitResult{}, &GnuplotError{err: "a fit needs at least one parameter"}
+	}
+	names := make([]string, 0, len(params))
+	for name, value := range params {
+		if !gIdentifier.MatchString(name) {
+			return FitResult{}, &GnuplotError{err: fmt.Sprintf("invalid parameter name '%s'", name)}
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return FitResult{}, &GnuplotError{err: fmt.Sprintf("invalid initial value %v of the parameter %s", value, name)}
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	options := FitOptions{}
+	for _, option := range opts {
+		option(&options)
+	}
+	if options.Curve != "" {
+		err := plot.checkNewName(options.Curve)
+		if err != nil {
+			return FitResult{}, err
+		}
+	}
+
+	err = plot.cmd("set fit quiet nolog errorvariables")
+	if err != nil {
+		return FitResult{}, err
+	}
+	for _, name := range names {
+		err = plot.cmd(name + " = " + formatReal(params[name]))
+		if err != nil {
+			return FitResult{}, err
+		}
+	}
+	ranges := "[*:*]"
+	if plot.dimensions == 3 {
+		ranges += " [*:*]"
+	}
+	err = plot.cmd(fmt.Sprintf("fit %s %s %s using %s via %s", ranges, expr, pointGroup.source, using, strings.Join(names, ",")))
+	if err != nil {
+		return FitResult{}, err
+	}
+
+	// the values and the errors of the parameters, then FIT_WSSR and FIT_NDF
+	values := make([]string, 0, 2*len(names)+2)
+	for _, name := range names {
+		values = append(values, name, name+"_err")
+	}
+	values = append(values, "FIT_WSSR", "FIT_NDF")
+	format := gFitPrefix + strings.Repeat(" %.17g", len(values))
+	output, err := plot.execContext(plot.ctx, fmt.Sprintf("print sprintf(%s, %s)", doubleQuote(format), strings.Join(values, ", ")))
+	if err != nil {
+		return FitResult{}, err
+	}
+	result, err := parseFitResult(output, names)
+	if err != nil {
+		return FitResult{}, err
+	}
+
+	// keep the fitted values for the multiplots and the curve of the model
+	for _, name := range names {
+		err = plot.set("variable "+name, name+" = "+formatReal(result.Params[name]))
+		if err != nil {
+			return FitResult{}, err
+		}
+	}
+	if options.Curve != "" {
+		err = plot.addExpression(options.Curve, expr, options.Style)
+		if err != nil {
+			return FitResult{}, err
+		}
+	}
+	return result, nil
+}
+
+// parseFitResult reads the results of a fit from the line gnuplot printed.
+func parseFitResult(output []string, names []string) (FitResult, error) {
+	for _, line := range output {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != gFitPrefix {
+			continue
+		}
+		fields = fields[1:]
+		if len(fields) != 2*len(names)+2 {
+			break
+		}
+		numbers := make([]float64, len(fields))
+		for i, field := range fields {
+			number, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return FitResult{}, &GnuplotError{err: fmt.Sprintf("invalid fit result %q: %v", line, err)}
+			}
+			numbers[i] = number
+		}
+		result := FitResult{
+			Params:           make(map[string]float64, len(names)),
+			Errors:           make(map[string]float64, len(names)),
+			DegreesOfFreedom: int(numbers[2*len(names)+1]),
+		}
+		for i, name := range names {
+			result.Params[name] = numbers[2*i]
+			result.Errors[name] = numbers[2*i+1]
+		}
+		result.ReducedChiSquare = math.NaN()
+		if result.DegreesOfFreedom > 0 {
+			result.ReducedChiSquare = numbers[2*len(names)] / float64(result.DegreesOfFreedom)
+		}
+		return result, nil
+	}
+	return FitResult{}, &GnuplotError{err: fmt.Sprintf("gnuplot didn't report the results of the fit: %q", strings.Join(output, "\n"))}
+}
@@ -0,0 +1,159 @@
+package glot
+
+import (
+	"fmt"
+	"math"
+)
+
+// gAdaptiveTolerance is the largest distance, relative to the height of the
+// curve, between a sampled curve and its chords accepted by adaptive sampling.
+const gAdaptiveTolerance = 1e-3
+
+// sampleFunc samples f at n points evenly spread from xmin to xmax.
+func sampleFunc(f func(float64) float64, xmin, xmax float64, n int) [][]float64 {
+	xs := linspace(xmin, xmax, n)
+	ys := make([]float64, len(xs))
+	for i, x := range xs {
+		ys[i] = f(x)
+	}
+	return [][]float64{xs, ys}
+}
+
+// refine adds samples in the middle of the segments where f bends, until the
+// curve is close to its chords or there are maxSamples samples.
+func refine(f func(float64) float64, xs, ys []float64, maxSamples int) ([]float64, []float64) {
+	for len(xs) < maxSamples {
+		low, high := math.Inf(1), math.Inf(-1)
+		for _, y := range ys {
+			if !math.IsNaN(y) && !math.IsInf(y, 0) {
+				low, high = min(low, y), max(high, y)
+			}
+		}
+		tolerance := gAdaptiveTolerance * (high - low)
+
+		refinedX := make([]float64, 0, 2*len(xs))
+		refinedY := make([]float64, 0, 2*len(ys))
+		added := false
+		for i := range xs {
+			refinedX = append(refinedX, xs[i])
+			refinedY = append(refinedY, ys[i])
+			if i == len(xs)-1 || len(xs)+len(refinedX)-i-1 >= maxSamples {
+				continue
+			}
+			x := (xs[i] + xs[i+1]) / 2
+			y := f(x)
+			if !bends(ys[i], y, ys[i+1], tolerance) {
+				continue
+			}
+			refinedX = append(refinedX, x)
+			refinedY = append(refinedY, y)
+			added = true
+		}
+		if !added {
+			break
+		}
+		xs, ys = refinedX, refinedY
+	}
+	return xs, ys
+}
+
+// bends reports whether the middle sample is far from the chord of the
+// samples around it. Segments where the function is defined on one end only
+// are refined to find where it stops being defined.
+func bends(before, middle, after, tolerance float64) bool {
+	defined := func(y float64) bool { return !math.IsNaN(y) && !math.IsInf(y, 0) }
+	if !defined(before) || !defined(middle) || !defined(after) {
+		return defined(before) != defined(after) || defined(middle) != defined(before)
+	}
+	return math.Abs(middle-(before+after)/2) > tolerance
+}
+
+// AddFunc samples f at the given number of points evenly spread from xmin to
+// xmax and adds the samples as a point group, so a model can be drawn over
+// measured data. The values where f returns NaN are left undrawn.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.AddPointGroup("measures", glot.StylePoints, [][]float64{xs, ys})
+//	plot.AddFunc("model", glot.StyleLines, func(x float64) float64 {
+//		return 2*x + 1
+//	}, 0, 10, 100)
+func (plot *plot) AddFunc(name string, style Style, f func(float64) float64, xmin, xmax float64, samples int, opts ...PointGroupOption) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.addFunc(name, style, f, xmin, xmax, samples, samples, opts)
+}
+
+// AddFuncAdaptive is like AddFunc but adds samples where f bends, up to
+// maxSamples in total, so sharp features are drawn smoothly without sampling
+// the whole range finely.
+//
+// Usage
+//
+//	plot.AddFuncAdaptive("response", glot.StyleLines, func(x float64) float64 {
+//		return math.Exp(-x*x*100)
+//	}, -1, 1, 50, 1000)
+func (plot *plot) AddFuncAdaptive(name string, style Style, f func(float64) float64, xmin, xmax float64, samples, maxSamples int, opts ...PointGroupOption) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	if maxSamples < samples {
+		return &GnuplotError{err: fmt.Sprintf("the maximum number of samples %d is below the number of samples %d", maxSamples, samples)}
+	}
+	return plot.addFunc(name, style, f, xmin, xmax, samples, maxSamples, opts)
+}
+
+func (plot *plot) addFunc(name string, style Style, f func(float64) float64, xmin, xmax float64, samples, maxSamples int, opts []PointGroupOption) error {
+	err := plot.checkNewName(name)
+	if err != nil {
+		return err
+	}
+	if f == nil {
+		return &GnuplotError{err: "the function must not be nil"}
+	}
+	if plot.dimensions != 2 {
+		return &GnuplotError{err: "functions of x can only be drawn on 2 dimensional plots"}
+	}
+	if samples < 2 {
+		return &GnuplotError{err: fmt.Sprintf("a function needs at least 2 samples, got %d", samples)}
+	}
+	if !(xmin < xmax) {
+		return &GnuplotError{err: fmt.Sprintf("invalid range [%v:%v]", xmin, xmax)}
+	}
+
+	columns := sampleFunc(f, xmin, xmax, samples)
+	if maxSamples > samples {
+		columns[0], columns[1] = refine(f, columns[0], columns[1], maxSamples)
+	}
+	curve := &pointGroup{name: name, dimensions: 2, data: columns, set: true, style: string(style)}
+	curve.options.apply(opts)
+	err = curve.options.check(plot.dimensions)
+	if err != nil {
+		return err
+	}
+	curve.columns = columns
+	return plot.addPointGroup(curve)
+}
+
+// AddSurfaceFunc samples f on a mesh of nx by ny points evenly spread over
+// the given ranges and adds the samples as a surface, see AddSurface.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(3, false)
+//	plot.AddSurfaceFunc("model", glot.StylePm3d, func(x, y float64) float64 {
+//		return math.Sin(x) * math.Cos(y)
+//	}, -math.Pi, math.Pi, 40, -math.Pi, math.Pi, 40)
+func (plot *plot) AddSurfaceFunc(name string, style Style, f func(x, y float64) float64, xmin, xmax float64, nx int, ymin, ymax float64, ny int, opts ...PointGroupOption) error {
+	if f == nil {
+		return &GnuplotError{err: "the function must not be nil"}
+	}
+	if nx < 2 || ny < 2 {
+		return &GnuplotError{err: fmt.Sprintf("a surface needs at least 2x2 samples, got %dx%d", nx, ny)}
+	}
+	if !(xmin < xmax) || !(ymin < ymax) {
+		return &GnuplotError{err: fmt.Sprintf("invalid ranges [%v:%v] and [%v:%v]", xmin, xmax, ymin, ymax)}
+	}
+	return plot.AddSurface(name, style, SampleGrid(f, xmin, xmax, nx, ymin, ymax, ny), opts...)
+}
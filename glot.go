@@ -8,8 +8,11 @@
 package glot
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"sync"
+	"time"
 )
 
 type Style string
@@ -28,13 +31,52 @@ const (
 	StyleBoxErrorBars Style = "boxerrorbars"
 	StyleBoxes        Style = "boxes"
 	StyleLp           Style = "lp"
+	StylePm3d         Style = "pm3d"
+	StyleYErrorBars   Style = "yerrorbars"
+	StyleXErrorBars   Style = "xerrorbars"
+	StyleXYErrorBars  Style = "xyerrorbars"
+	StyleYErrorLines  Style = "yerrorlines"
+	StyleXErrorLines  Style = "xerrorlines"
+	StyleXYErrorLines Style = "xyerrorlines"
+	StyleCandlesticks Style = "candlesticks"
+	StyleFinanceBars  Style = "financebars"
+	StyleFilledCurves Style = "filledcurves"
 )
 
+// Format is an output format of the plot, named after the gnuplot terminal
+// producing it. See Terminal for the options of every format.
 type Format string
 
 const (
-	FormatPng Format = "png"
-	FormatPdf Format = "pdf"
+	FormatPng        Format = "png"
+	FormatPngCairo   Format = "pngcairo"
+	FormatJpeg       Format = "jpeg"
+	FormatGif        Format = "gif"
+	FormatSixel      Format = "sixelgd"
+	FormatSvg        Format = "svg"
+	FormatCanvas     Format = "canvas"
+	FormatPdf        Format = "pdf"
+	FormatPdfCairo   Format = "pdfcairo"
+	FormatPostscript Format = "postscript"
+	FormatEps        Format = "eps"
+	FormatDumb       Format = "dumb"
+	FormatLatex      Format = "latex"
+	FormatTikz       Format = "tikz"
+)
+
+// DataTransport selects how the data of the point groups is sent to gnuplot.
+type DataTransport int
+
+const (
+	// TransportFile writes every point group to a temporary file which is
+	// removed when the point group is removed or the plot is closed.
+	TransportFile DataTransport = iota
+	// TransportDataBlock sends every point group over the gnuplot stdin
+	// as a named data block, so no temporary files are created.
+	TransportDataBlock
+	// TransportBinary writes every point group to a temporary file as raw
+	// float64 values, which is much faster than text for large point groups.
+	TransportBinary
 )
 
 // Plot is the basic type representing a plot.
@@ -47,15 +89,37 @@ const (
 // The Pointgroups can be dynamically added and removed from a plot
 // And style changes can also be made dynamically.
 // Plot is an interface for plotting data in 1, 2 or 3 dimensions
+//
+// All the methods of a Plot are safe for concurrent use. Calls are
+// serialized: every method holds the plot for the whole exchange with
+// gnuplot, so commands sent from different goroutines never interleave.
+// A context passed to a method bounds the exchange itself, not the time
+// spent waiting for other calls to finish. Plots and multiplots can be
+// created from several goroutines as well.
 type Plot interface {
 	// AddPointGroup adds a new point group with the given name and style
-	AddPointGroup(name string, style Style, points any) error
+	AddPointGroup(name string, style Style, points any, opts ...PointGroupOption) error
 
 	// RemovePointGroup helps to remove a particular point group from the plot.
-	RemovePointGroup(name string)
+	RemovePointGroup(name string) error
+
+	// UpdatePointGroup replaces the data of a point group in place.
+	UpdatePointGroup(name string, data any) error
+
+	// UpdateTimeSeries replaces the times and the values of a time series in place.
+	UpdateTimeSeries(name string, times []time.Time, values any) error
+
+	// RenamePointGroup changes the name of a point group.
+	RenamePointGroup(name, newName string) error
+
+	// ListPointGroups returns the names of the point groups in drawing order.
+	ListPointGroups() []string
+
+	// MovePointGroup moves a point group to the given position in the drawing order.
+	MovePointGroup(name string, index int) error
 
 	// ResetPointGroupStyle helps to reset the style of a particular point group in a plot.
-	ResetPointGroupStyle(name string, style string) error
+	ResetPointGroupStyle(name string, style Style, opts ...PointGroupOption) error
 
 	// SetTitle sets the title for the plot
 	SetTitle(title string) error
@@ -69,43 +133,175 @@ type Plot interface {
 	// SetZLabel sets the label for the z-axis
 	SetZLabel(label string) error
 
+	// SetX2Label sets the label for the secondary x-axis
+	SetX2Label(label string) error
+
+	// SetY2Label sets the label for the secondary y-axis
+	SetY2Label(label string) error
+
 	// SetLabels sets labels for x, y, z axes simultaneously
 	SetLabels(labels ...string) error
 
-	// SetXrange sets the range for the x-axis
+	// SetXrange sets the range for the x-axis, see SetRange for non integer bounds
 	SetXrange(start int, end int) error
 
 	// SetLogscale changes the label for the x-axis
 	SetLogscale(axis string, base int) error
 
-	// SetYrange changes the label for the y-axis
+	// SetYrange changes the label for the y-axis, see SetRange for non integer bounds
 	SetYrange(start int, end int) error
 
-	// SetZrange changes the label for the z-axis
+	// SetZrange changes the label for the z-axis, see SetRange for non integer bounds
 	SetZrange(start int, end int) error
 
+	// SetX2range changes the range for the secondary x-axis
+	SetX2range(r Range) error
+
+	// SetY2range changes the range for the secondary y-axis
+	SetY2range(r Range) error
+
+	// SetX2Tics shows tics on the secondary x-axis
+	SetX2Tics() error
+
+	// SetY2Tics shows tics on the secondary y-axis
+	SetY2Tics() error
+
+	// SetPointGroupAxes binds a point group to a pair of axes
+	SetPointGroupAxes(name string, axes Axes) error
+
+	// SetRange sets the range of any axis
+	SetRange(axis Axis, r Range) error
+
+	// AddHeatmap adds a heatmap drawing a matrix as a grid of colored cells.
+	AddHeatmap(name string, matrix any, opts ...HeatmapOption) error
+
+	// AddBarChart adds a bar chart of named series over categories.
+	AddBarChart(name string, categories []string, series []BarSeries, opts ...BarChartOption) error
+
+	// AddHistogram bins the values and adds the histogram as boxes.
+	AddHistogram(name string, values any, opts ...HistogramOption) error
+
+	// AddSurface adds a surface sampled on a regular grid.
+	AddSurface(name string, style Style, grid Grid, opts ...PointGroupOption) error
+
+	// AddFunc samples a function of x and adds the samples as a point group.
+	AddFunc(name string, style Style, f func(float64) float64, xmin, xmax float64, samples int, opts ...PointGroupOption) error
+
+	// AddFuncAdaptive samples a function of x more finely where it bends.
+	AddFuncAdaptive(name string, style Style, f func(float64) float64, xmin, xmax float64, samples, maxSamples int, opts ...PointGroupOption) error
+
+	// AddSurfaceFunc samples a function of x and y and adds the samples as a surface.
+	AddSurfaceFunc(name string, style Style, f func(x, y float64) float64, xmin, xmax float64, nx int, ymin, ymax float64, ny int, opts ...PointGroupOption) error
+
+	// AddExpression adds a curve drawn by gnuplot from an expression.
+	AddExpression(name, expr string, opts ...PointGroupOption) error
+
+	// DefineFunction defines a gnuplot function used by the expressions.
+	DefineFunction(signature, body string) error
+
+	// SetVariable sets a gnuplot variable used by the expressions.
+	SetVariable(name string, value float64) error
+
+	// Fit fits a model to the data of a point group and returns its parameters.
+	Fit(groupName, expr string, params map[string]float64, opts ...FitOption) (FitResult, error)
+
+	// AddOverlay adds curves computed in Go from the points of a point group.
+	AddOverlay(name, groupName string, overlay Overlay, opts ...PointGroupOption) error
+
+	// SetPm3d colors the surfaces according to the palette.
+	SetPm3d(positions ...Pm3dPosition) error
+
+	// SetContour draws contour lines of the surfaces.
+	SetContour(options ContourOptions) error
+
+	// SetView sets the angles the 3 dimensional plot is seen from.
+	SetView(rotX, rotZ float64) error
+
+	// SetViewMap shows the 3 dimensional plot from above.
+	SetViewMap() error
+
+	// SetHidden3d hides the lines behind the surfaces.
+	SetHidden3d() error
+
+	// SetDgrid3d interpolates scattered points on a grid.
+	SetDgrid3d(rows, cols int) error
+
+	// AddTimeSeries adds a point group whose x coordinates are times.
+	AddTimeSeries(name string, style Style, times []time.Time, values any, opts ...PointGroupOption) error
+
+	// SetTimeAxis makes the axis show time data formatted with format.
+	SetTimeAxis(axis Axis, format string) error
+
+	// SetTimeFormat sets the format gnuplot uses to read times given as strings.
+	SetTimeFormat(format string) error
+
+	// SetTimeLocation sets the time zone times are shown in.
+	SetTimeLocation(loc *time.Location) error
+
+	// SetTimeTics places the major tics of a time axis at the given interval.
+	SetTimeTics(axis Axis, interval time.Duration) error
+
 	// SavePlot function is used to save the plot at this point.
 	SavePlot(filename string, w, h int) error
 
+	// SavePlotContext is like SavePlot but gives up when ctx is done.
+	SavePlotContext(ctx context.Context, filename string, w, h int) error
+
+	// Render writes the plot encoded in the given format to w.
+	Render(w io.Writer, format Format, width, height int) error
+
+	// RenderContext is like Render but gives up when ctx is done.
+	RenderContext(ctx context.Context, w io.Writer, format Format, width, height int) error
+
+	// Bytes returns the plot encoded in the given format.
+	Bytes(format Format, width, height int) ([]byte, error)
+
 	// SetFormat sets the output format (png, pdf, etc)
 	SetFormat(format Format) error
 
+	// SetTerminal sets the output format together with the terminal options
+	SetTerminal(terminal Terminal) error
+
+	// SetDataTransport selects how the data of new point groups is sent to gnuplot
+	SetDataTransport(transport DataTransport) error
+
+	// SetBinaryThreshold sets the size above which point groups are sent in binary format
+	SetBinaryThreshold(points int) error
+
 	SetKeyOutside() error
 
 	SetGrid() error
+
+	// Close stops the gnuplot process and releases the plot resources.
+	Close() error
 }
 
 // plot implements the Plot interface
 type plot struct {
-	proc       *plotterProcess
-	plotCmd    string
-	nPlots     int                    // number of currently active plots
-	tmpFiles   tempFilesDb            // A temporary file used for saving data
-	dimensions int                    // dimensions of the plot
-	pointGroup map[string]*pointGroup // A map between Curve name and curve type. This maps a name to a given curve in a plot. Only one curve with a given name exists in a plot.
-	format     Format                 // The saving format of the plot. This could be PDF, PNG, JPEG and so on.
-	style      string                 // style of the plot
-	title      string                 // The title of the plot.
+	mu              sync.Mutex      // serializes the calls, guards all the fields below and the gnuplot pipes
+	ctx             context.Context // context bounding the lifetime of the gnuplot process
+	stopCleanup     func() bool     // stops removing the temporary files when ctx is done
+	proc            *plotterProcess
+	plotCmd         string                 // plot for 1 and 2 dimensional plots, splot for 3 dimensional ones
+	tmpFiles        tempFilesDb            // A temporary file used for saving data
+	transport       DataTransport          // how the data of new point groups is sent to gnuplot
+	binaryThreshold int                    // point groups of at least this size are sent in binary format
+	nBlocks         int                    // number of data blocks defined so far, used to name them
+	dimensions      int                    // dimensions of the plot
+	location        *time.Location         // time zone the times are shown in
+	timeAxes        map[Axis]bool          // axes showing time data
+	settings        []setting              // commands setting the options of the plot, in the order they were first run
+	order           []string               // names of the point groups in the order they are drawn
+	pointGroup      map[string]*pointGroup // A map between Curve name and curve type. This maps a name to a given curve in a plot. Only one curve with a given name exists in a plot.
+	terminal        Terminal               // The saving format of the plot. This could be PDF, PNG, JPEG and so on.
+	style           string                 // style of the plot
+	title           string                 // The title of the plot.
+}
+
+// setting is a command changing an option of the plot, see plot.set.
+type setting struct {
+	key     string
+	command string
 }
 
 // NewPlot Function makes a new plot with the specified dimensions.
@@ -122,22 +318,47 @@ type plot struct {
 //	dimensions  :=> refers to the dimensions of the plot.
 //	persist     :=> used to make the gnu plot window stay open.
 func NewPlot(dimensions int, persist bool) (Plot, error) {
-	err := sync.OnceValue(initialize)()
+	return NewPlotContext(context.Background(), dimensions, persist)
+}
+
+// NewPlotContext is like NewPlot but binds the gnuplot process to ctx.
+// When ctx is done the process is killed, the temporary files of the plot
+// are removed and every pending or later call returns ctx.Err().
+//
+// Usage
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//	plot, _ := glot.NewPlotContext(ctx, 2, false)
+//	defer plot.Close()
+func NewPlotContext(ctx context.Context, dimensions int, persist bool) (Plot, error) {
+	err := initGnuplot()
 	if err != nil {
 		return nil, err
 	}
-	p := &plot{proc: nil, plotCmd: "plot",
-		nPlots: 0, dimensions: dimensions, style: "points", format: "png"}
+	// Only 1,2,3 Dimensional plots are supported
+	if dimensions > 3 || dimensions < 1 {
+		return nil, &GnuplotError{err: fmt.Sprintf("invalid number of dims '%v'", dimensions)}
+	}
+	p := &plot{ctx: ctx, proc: nil, plotCmd: "plot",
+		dimensions: dimensions, style: "points", terminal: PngOptions{}}
+	if dimensions == 3 {
+		p.plotCmd = "splot"
+	}
 	p.pointGroup = make(map[string]*pointGroup) // Adding a mapping between a curve name and a curve
 	p.tmpFiles = make(tempFilesDb)
-	proc, err := newPlotterProc(persist)
+	p.location = time.UTC
+	p.timeAxes = make(map[Axis]bool)
+	proc, err := newPlotterProc(ctx, persist)
 	if err != nil {
 		return nil, err
 	}
-	// Only 1,2,3 Dimensional plots are supported
-	if dimensions > 3 || dimensions < 1 {
-		return nil, &gnuplotError{fmt.Sprintf("invalid number of dims '%v'", dimensions)}
-	}
 	p.proc = proc
+	// the plot may be idle when ctx is done, so nothing else would notice it
+	p.stopCleanup = context.AfterFunc(ctx, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.tmpFiles.remove()
+	})
 	return p, nil
 }
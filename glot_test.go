@@ -0,0 +1,287 @@
+package glot
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gFakeGnuplotEnv makes the test binary behave as gnuplot, see fakeGnuplot.
+const gFakeGnuplotEnv = "GLOT_FAKE_GNUPLOT"
+
+// gFakeImage is the image the fake gnuplot draws for every plot.
+var gFakeImage = []byte("fake image\x00\x01\n")
+
+var (
+	gFakeBlock  = regexp.MustCompile(`^\$\w+\s*<<\s*(\w+)$`)
+	gFakePrint  = regexp.MustCompile(`^print\s+(?:"(.*)"|'(.*)')$`)
+	gFakeOutput = regexp.MustCompile(`^set output\s*(?:'(.*)')?$`)
+	gFakePlot   = regexp.MustCompile(`^(plot|splot|replot)\b`)
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv(gFakeGnuplotEnv) != "" {
+		fakeGnuplot(os.Stdin, os.Stdout, os.Stderr)
+		os.Exit(0)
+	}
+	// the plots run the test binary itself as gnuplot
+	executable, err := os.Executable()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	SetCustomPathToGNUPlot(executable)
+	os.Setenv(gFakeGnuplotEnv, "1")
+	os.Exit(m.Run())
+}
+
+// fakeGnuplot understands just enough of the gnuplot commands for the
+// tests: it skips the data blocks, prints the strings given to print, which
+// include the synchronization markers, to stderr or to stdout after
+// "set print '-'", and writes gFakeImage to the output for every plot. The
+// commands containing "bogus" are reported as errors.
+func fakeGnuplot(stdin io.Reader, stdout, stderr io.Writer) {
+	printer := stderr
+	var output io.Writer = stdout
+	var file *os.File
+	lines := bufio.NewScanner(stdin)
+	lines.Buffer(nil, 64*1024*1024)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if match := gFakeBlock.FindStringSubmatch(line); match != nil {
+			for lines.Scan() && strings.TrimSpace(lines.Text()) != match[1] {
+			}
+			continue
+		}
+		switch {
+		case strings.Contains(line, "bogus"):
+			fmt.Fprintf(stderr, "%s\n         ^\n         line 0: unrecognized option\n\n", line)
+		case gFakePrint.MatchString(line):
+			match := gFakePrint.FindStringSubmatch(line)
+			fmt.Fprintln(printer, match[1]+match[2])
+		case line == "set print '-'":
+			printer = stdout
+		case line == "set print":
+			printer = stderr
+		case gFakeOutput.MatchString(line):
+			if file != nil {
+				file.Close()
+				file = nil
+			}
+			output = stdout
+			name := gFakeOutput.FindStringSubmatch(line)[1]
+			if name != "" {
+				var err error
+				file, err = os.Create(name)
+				if err != nil {
+					fmt.Fprintf(stderr, "line 0: cannot open file; output not changed\n")
+					continue
+				}
+				output = file
+			}
+		case gFakePlot.MatchString(line):
+			output.Write(gFakeImage)
+		}
+	}
+	if file != nil {
+		file.Close()
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	plot, err := NewPlot(2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plot.Close()
+	dir := t.TempDir()
+
+	const goroutines = 8
+	const rounds = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines*rounds*4+goroutines*2)
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// the plots are created concurrently too
+			own, err := NewPlot(2, false)
+			if err == nil {
+				err = own.Close()
+			}
+			errs <- err
+			multiplot, err := NewMultiplot(1, 2)
+			if err == nil {
+				err = multiplot.Close()
+			}
+			errs <- err
+
+			for r := range rounds {
+				name := fmt.Sprintf("curve %d-%d", g, r)
+				errs <- plot.AddPointGroup(name, StyleLines, []float64{1, 2, float64(r)})
+				errs <- plot.UpdatePointGroup(name, [][]float64{{1, 2, 3}, {4, 5, float64(g)}})
+				errs <- plot.SavePlot(filepath.Join(dir, fmt.Sprintf("%d-%d.png", g, r)), 320, 240)
+
+				var image strings.Builder
+				err := plot.Render(&image, FormatPng, 320, 240)
+				if err == nil && image.String() != string(gFakeImage) {
+					err = fmt.Errorf("rendered %q instead of %q", image.String(), gFakeImage)
+				}
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if n := len(plot.ListPointGroups()); n != goroutines*rounds {
+		t.Errorf("the plot has %d point groups instead of %d", n, goroutines*rounds)
+	}
+}
+
+func TestRejectedStyleIsRolledBack(t *testing.T) {
+	plot, err := NewPlot(2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plot.Close()
+
+	err = plot.AddPointGroup("good", StyleLines, []float64{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = plot.AddPointGroup("bad", "bogus", []float64{1, 2})
+	if err == nil {
+		t.Fatal("gnuplot accepted a bogus style")
+	}
+	err = plot.AddPointGroup("next", StyleLines, []float64{3, 4})
+	if err != nil {
+		t.Fatalf("the rejected point group broke the plot: %v", err)
+	}
+	if groups := plot.ListPointGroups(); len(groups) != 2 {
+		t.Errorf("got point groups %v, want good and next", groups)
+	}
+}
+
+func TestStyleNeedingMoreColumnsIsRejected(t *testing.T) {
+	plot, err := NewPlot(2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plot.Close()
+
+	err = plot.AddPointGroup("good", StyleLines, [][]float64{{1, 2}, {3, 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, style := range []Style{StyleCandlesticks, StyleXYErrorBars} {
+		err = plot.ResetPointGroupStyle("good", style)
+		if err == nil {
+			t.Errorf("the style %s was accepted for 2 columns", style)
+		}
+	}
+	err = plot.ResetPointGroupStyle("good", StylePoints)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRejectedRenameIsRolledBack(t *testing.T) {
+	plot, err := NewPlot(2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plot.Close()
+
+	err = plot.AddPointGroup("good", StyleLines, []float64{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = plot.RenamePointGroup("good", "bogus")
+	if err == nil {
+		t.Fatal("gnuplot accepted a bogus name")
+	}
+	if groups := plot.ListPointGroups(); len(groups) != 1 || groups[0] != "good" {
+		t.Errorf("got point groups %v, want good", groups)
+	}
+	err = plot.AddPointGroup("next", StyleLines, []float64{3, 4})
+	if err != nil {
+		t.Fatalf("the rejected name broke the plot: %v", err)
+	}
+	err = plot.MovePointGroup("next", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groups := plot.ListPointGroups(); len(groups) != 2 || groups[0] != "next" {
+		t.Errorf("got point groups %v, want next and good", groups)
+	}
+}
+
+func TestRemovePointGroup(t *testing.T) {
+	plot, err := NewPlot(2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plot.Close()
+
+	for _, name := range []string{"first", "second"} {
+		err = plot.AddPointGroup(name, StyleLines, []float64{1, 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = plot.RemovePointGroup("first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groups := plot.ListPointGroups(); len(groups) != 1 || groups[0] != "second" {
+		t.Errorf("got point groups %v, want second", groups)
+	}
+	err = plot.AddPointGroup("first", StyleLines, []float64{3, 4})
+	if err != nil {
+		t.Fatalf("the removed name can't be used again: %v", err)
+	}
+}
+
+func TestRejectedPointGroupRecordsNoSettings(t *testing.T) {
+	tests := map[string]func(p Plot) error{
+		"bar chart": func(p Plot) error {
+			return p.AddBarChart("bars", []string{"eu", "us"}, []BarSeries{{Name: "bogus", Values: []int{1, 2}}})
+		},
+		"time series": func(p Plot) error {
+			return p.AddTimeSeries("bogus", StyleLines, []time.Time{time.Unix(0, 0), time.Unix(60, 0)}, []float64{1, 2})
+		},
+		"heatmap": func(p Plot) error {
+			return p.AddHeatmap("heat", [][]float64{{1, 2}, {3, 4}},
+				WithPalette(PaletteViridis), WithCBLabel("ms"), WithColumnLabels("a", "b"), WithCellLabels("bogus %f"))
+		},
+	}
+	for name, add := range tests {
+		t.Run(name, func(t *testing.T) {
+			p, err := NewPlot(2, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer p.Close()
+
+			err = add(p)
+			if err == nil {
+				t.Fatal("gnuplot accepted a bogus point group")
+			}
+			if settings := p.(*plot).settings; len(settings) != 0 {
+				t.Errorf("the rejected point group recorded the settings %v", settings)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package glot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Palette is a gnuplot color palette, mapping the values of heatmaps and of
+// other palette based plots to colors. It holds the options of the gnuplot
+// "set palette" command.
+type Palette string
+
+const (
+	PaletteGray    Palette = "gray"                                     // black to white
+	PaletteHot     Palette = "rgbformulae 21,22,23"                     // black, red, yellow, white
+	PaletteRainbow Palette = "rgbformulae 33,13,10"                     // blue, green, yellow, red
+	PaletteBlueRed Palette = "defined (0 'blue', 0.5 'white', 1 'red')" // diverging palette centered on white
+	PaletteViridis Palette = "defined (0 '#440154', 0.25 '#3b528b', 0.5 '#21918c', 0.75 '#5ec962', 1 '#fde725')"
+)
+
+// PaletteColors returns a palette going through the colors at regular steps.
+//
+// Usage
+//
+//	glot.PaletteColors("white", "orange", "dark-red")
+func PaletteColors(colors ...Color) Palette {
+	if len(colors) == 1 {
+		colors = append(colors, colors[0])
+	}
+	stops := make([]string, len(colors))
+	for i, color := range colors {
+		position := float64(i) / float64(len(colors)-1)
+		stops[i] = formatFloat(position) + " " + quote(string(color))
+	}
+	return Palette("defined (" + strings.Join(stops, ", ") + ")")
+}
+
+// HeatmapOptions describes how a heatmap is drawn.
+// The zero value of every field keeps the gnuplot default.
+type HeatmapOptions struct {
+	Palette      Palette  // color palette of the plot
+	CBRange      *Range   // range of the values mapped to the palette, autoscaled if nil
+	CBLabel      string   // label of the color box
+	LabelFormat  string   // printf like format of the values written on the cells, no labels if empty
+	ColumnLabels []string // tic labels of the columns, on the x axis
+	RowLabels    []string // tic labels of the rows, on the y axis
+}
+
+// HeatmapOption changes the options of a heatmap.
+type HeatmapOption func(*HeatmapOptions)
+
+// WithPalette sets the color palette of the plot.
+func WithPalette(palette Palette) HeatmapOption {
+	return func(opts *HeatmapOptions) {
+		opts.Palette = palette
+	}
+}
+
+// WithCBRange sets the range of the values mapped to the palette.
+func WithCBRange(r Range) HeatmapOption {
+	return func(opts *HeatmapOptions) {
+		opts.CBRange = &r
+	}
+}
+
+// WithCBLabel sets the label of the color box.
+func WithCBLabel(label string) HeatmapOption {
+	return func(opts *HeatmapOptions) {
+		opts.CBLabel = label
+	}
+}
+
+// WithCellLabels writes the value of every cell on it, formatted with the
+// printf like format, e.g. "%.1f".
+func WithCellLabels(format string) HeatmapOption {
+	return func(opts *HeatmapOptions) {
+		opts.LabelFormat = format
+	}
+}
+
+// WithColumnLabels names the columns of the matrix on the x axis.
+func WithColumnLabels(labels ...string) HeatmapOption {
+	return func(opts *HeatmapOptions) {
+		opts.ColumnLabels = labels
+	}
+}
+
+// WithRowLabels names the rows of the matrix on the y axis.
+func WithRowLabels(labels ...string) HeatmapOption {
+	return func(opts *HeatmapOptions) {
+		opts.RowLabels = labels
+	}
+}
+
+// matrixColumns converts a matrix given as rows of numbers to float64
+// columns, one slice per column of the matrix.
+func matrixColumns(data any) ([][]float64, error) {
+	rows, err := castData(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return nil, &GnuplotError{err: "the matrix of a heatmap must not be empty"}
+	}
+	columns := make([][]float64, len(rows[0]))
+	for j := range columns {
+		columns[j] = make([]float64, len(rows))
+	}
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return nil, &GnuplotError{err: fmt.Sprintf("row %d of the matrix has %d values instead of %d", i, len(row), len(columns))}
+		}
+		for j, value := range row {
+			columns[j][i] = value
+		}
+	}
+	return columns, nil
+}
+
+// ticLabels returns the tic labels placed at the indices of the matrix.
+func ticLabels(labels []string) string {
+	tics := make([]string, len(labels))
+	for i, label := range labels {
+		tics[i] = doubleQuote(label) + " " + strconv.Itoa(i)
+	}
+	return "(" + strings.Join(tics, ", ") + ")"
+}
+
+// AddHeatmap adds a heatmap drawing a matrix, given as rows of numbers, as a
+// grid of cells colored by their values. The cell of row i and column j is
+// centered on x = j, y = i, so the first row is at the bottom of the plot.
+// The palette, the color box and the tic labels set by the options apply to
+// the whole plot. The name identifies the heatmap but is not shown in the key.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.AddHeatmap("latency", [][]float64{{12, 15, 40}, {11, 18, 35}},
+//		glot.WithPalette(glot.PaletteViridis),
+//		glot.WithCBLabel("ms"),
+//		glot.WithCellLabels("%.0f"),
+//		glot.WithColumnLabels("00:00", "01:00", "02:00"),
+//		glot.WithRowLabels("eu", "us"))
+func (plot *plot) AddHeatmap(name string, matrix any, opts ...HeatmapOption) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	err := plot.checkNewName(name)
+	if err != nil {
+		return err
+	}
+	if plot.dimensions != 2 {
+		return &GnuplotError{err: "heatmaps can only be drawn on 2 dimensional plots"}
+	}
+	columns, err := matrixColumns(matrix)
+	if err != nil {
+		return err
+	}
+	options := &HeatmapOptions{}
+	for _, option := range opts {
+		option(options)
+	}
+
+	curve := &pointGroup{name: name, dimensions: 3, data: matrix, set: true, style: "image"}
+	curve.heatmap = options
+	curve.columns = columns
+	err = plot.addPointGroup(curve)
+	if err != nil {
+		return err
+	}
+
+	// the settings are kept only for a heatmap gnuplot accepted
+	if options.Palette != "" {
+		err = plot.set("palette", "set palette "+string(options.Palette))
+		if err != nil {
+			return err
+		}
+	}
+	if options.CBRange != nil {
+		err = plot.setRange(AxisCB, *options.CBRange)
+		if err != nil {
+			return err
+		}
+	}
+	if options.CBLabel != "" {
+		err = plot.set("cblabel", "set cblabel "+quote(options.CBLabel))
+		if err != nil {
+			return err
+		}
+	}
+	if len(options.ColumnLabels) > 0 {
+		err = plot.set("xtics", "set xtics "+ticLabels(options.ColumnLabels))
+		if err != nil {
+			return err
+		}
+	}
+	if len(options.RowLabels) > 0 {
+		err = plot.set("ytics", "set ytics "+ticLabels(options.RowLabels))
+		if err != nil {
+			return err
+		}
+	}
+	return plot.replot()
+}
+
+// heatmapElement returns the part of the plot command drawing the heatmap
+// and its cell labels.
+func (pointGroup *pointGroup) heatmapElement() string {
+	element := pointGroup.source + " notitle with image"
+	if pointGroup.heatmap.LabelFormat != "" {
+		element += fmt.Sprintf(", %s using 1:2:(sprintf(%s, $3)) notitle with labels",
+			pointGroup.source, doubleQuote(pointGroup.heatmap.LabelFormat))
+	}
+	return element
+}
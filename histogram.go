@@ -0,0 +1,287 @@
+package glot
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+)
+
+// BinRule chooses the number of bins of a histogram from its values.
+// BinFreedmanDiaconis falls back to BinSturges when outliers would make more
+// bins than values.
+type BinRule int
+
+const (
+	BinSturges          BinRule = iota // log2(n)+1 bins, fine for roughly normal data
+	BinFreedmanDiaconis                // bins of width 2*IQR/n^(1/3), robust to outliers
+)
+
+// gMaxBins is the largest number of bins of a histogram.
+const gMaxBins = 100_000
+
+// HistogramOptions describes how the values of a histogram are binned.
+// The zero value bins the values with the Sturges rule and counts them.
+// A histogram has at most 100000 bins: more Bins, or a Width splitting the
+// values in more bins, are rejected.
+type HistogramOptions struct {
+	Bins       int                // number of bins of the same width, chosen by Rule if 0
+	Width      float64            // width of the bins, used instead of Bins when positive
+	Edges      []float64          // increasing edges of the bins, used instead of Bins and Width
+	Rule       BinRule            // rule choosing the number of bins when neither Bins, Width nor Edges is set
+	Density    bool               // show the density of the values instead of their count, the bars have an area of 1 in total
+	Cumulative bool               // show the running total of the bars, which ends with 1 together with Density
+	Style      []PointGroupOption // color, fill and the other options of the boxes
+}
+
+// HistogramOption changes the options of a histogram.
+type HistogramOption func(*HistogramOptions)
+
+// WithBins splits the range of the values in the given number of bins.
+func WithBins(bins int) HistogramOption {
+	return func(opts *HistogramOptions) {
+		opts.Bins = bins
+	}
+}
+
+// WithBinWidth makes bins of the given width, aligned on its multiples.
+func WithBinWidth(width float64) HistogramOption {
+	return func(opts *HistogramOptions) {
+		opts.Width = width
+	}
+}
+
+// WithBinEdges makes the bins between the given increasing edges. The values
+// out of the edges are ignored.
+func WithBinEdges(edges ...float64) HistogramOption {
+	return func(opts *HistogramOptions) {
+		opts.Edges = edges
+	}
+}
+
+// WithBinRule sets the rule choosing the number of bins.
+func WithBinRule(rule BinRule) HistogramOption {
+	return func(opts *HistogramOptions) {
+		opts.Rule = rule
+	}
+}
+
+// WithDensity shows the density of the values instead of their count.
+func WithDensity() HistogramOption {
+	return func(opts *HistogramOptions) {
+		opts.Density = true
+	}
+}
+
+// WithCumulative shows the running total of the bars.
+func WithCumulative() HistogramOption {
+	return func(opts *HistogramOptions) {
+		opts.Cumulative = true
+	}
+}
+
+// WithHistogramStyle sets the options the boxes of the histogram are drawn with.
+func WithHistogramStyle(opts ...PointGroupOption) HistogramOption {
+	return func(options *HistogramOptions) {
+		options.Style = append(options.Style, opts...)
+	}
+}
+
+// check reports invalid options.
+func (opts *HistogramOptions) check() error {
+	if opts.Bins < 0 || opts.Bins > gMaxBins {
+		return &GnuplotError{err: fmt.Sprintf("invalid number of bins %d", opts.Bins)}
+	}
+	if opts.Width < 0 || math.IsNaN(opts.Width) || math.IsInf(opts.Width, 0) {
+		return &GnuplotError{err: fmt.Sprintf("invalid bin width %v", opts.Width)}
+	}
+	if opts.Edges != nil {
+		if len(opts.Edges) < 2 {
+			return &GnuplotError{err: "a histogram needs at least 2 bin edges"}
+		}
+		for i := 1; i < len(opts.Edges); i++ {
+			if !(opts.Edges[i] > opts.Edges[i-1]) {
+				return &GnuplotError{err: fmt.Sprintf("the bin edges must be increasing, got %v after %v", opts.Edges[i], opts.Edges[i-1])}
+			}
+		}
+	}
+	if opts.Rule != BinSturges && opts.Rule != BinFreedmanDiaconis {
+		return &GnuplotError{err: fmt.Sprintf("unknown bin rule %d", opts.Rule)}
+	}
+	return nil
+}
+
+// edges returns the edges of the bins of the sorted values.
+func (opts *HistogramOptions) edges(sorted []float64) ([]float64, error) {
+	if opts.Edges != nil {
+		return opts.Edges, nil
+	}
+	low, high := sorted[0], sorted[len(sorted)-1]
+	if opts.Width > 0 {
+		start := math.Floor(low/opts.Width) * opts.Width
+		// counted in float64 first, a tiny width overflows an int
+		bins := math.Floor((high-start)/opts.Width) + 1
+		if bins > gMaxBins {
+			return nil, &GnuplotError{err: fmt.Sprintf("the bin width %v splits the values in %.0f bins, more than %d", opts.Width, bins, gMaxBins)}
+		}
+		edges := make([]float64, int(bins)+1)
+		for i := range edges {
+			edges[i] = start + float64(i)*opts.Width
+		}
+		return edges, nil
+	}
+	if low == high {
+		// a single bin of width 1 centered on the only value
+		return []float64{low - 0.5, low + 0.5}, nil
+	}
+	bins := opts.Bins
+	if bins == 0 {
+		bins = binCount(sorted, opts.Rule)
+	}
+	return linspace(low, high, bins+1), nil
+}
+
+// binCount returns the number of bins the rule chooses for the sorted values.
+func binCount(sorted []float64, rule BinRule) int {
+	n := float64(len(sorted))
+	sturges := int(math.Ceil(math.Log2(n))) + 1
+	if rule != BinFreedmanDiaconis {
+		return sturges
+	}
+	iqr := quantile(sorted, 0.75) - quantile(sorted, 0.25)
+	if iqr == 0 {
+		return sturges
+	}
+	width := 2 * iqr / math.Cbrt(n)
+	bins := math.Ceil((sorted[len(sorted)-1] - sorted[0]) / width)
+	if bins > n {
+		// outliers far from the bulk of the values would leave most bins empty
+		return sturges
+	}
+	return min(max(1, int(bins)), gMaxBins)
+}
+
+// quantile returns the q-quantile of the sorted values, interpolating
+// linearly between the closest ranks.
+func quantile(sorted []float64, q float64) float64 {
+	position := q * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	if lower+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	fraction := position - float64(lower)
+	return sorted[lower] + fraction*(sorted[lower+1]-sorted[lower])
+}
+
+// bin bins the values and returns the centers, the heights and the widths
+// of the bars.
+func (opts *HistogramOptions) bin(data any) ([][]float64, error) {
+	columns, err := castData(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) != 1 {
+		return nil, &GnuplotError{err: fmt.Sprintf("the values of a histogram must have 1 dimension, got %d", len(columns))}
+	}
+	sorted := slices.DeleteFunc(slices.Clone(columns[0]), func(v float64) bool {
+		return math.IsNaN(v) || math.IsInf(v, 0)
+	})
+	if len(sorted) == 0 {
+		return nil, &GnuplotError{err: "a histogram needs at least one finite value"}
+	}
+	slices.Sort(sorted)
+
+	edges, err := opts.edges(sorted)
+	if err != nil {
+		return nil, err
+	}
+	nbins := len(edges) - 1
+	counts := make([]float64, nbins)
+	total := 0
+	for _, value := range sorted {
+		if value < edges[0] || value > edges[nbins] {
+			continue
+		}
+		// the last bin includes its upper edge
+		i := sort.SearchFloat64s(edges, value)
+		if i == nbins || edges[i] > value {
+			i--
+		}
+		counts[i]++
+		total++
+	}
+
+	centers := make([]float64, nbins)
+	widths := make([]float64, nbins)
+	for i := range counts {
+		centers[i] = (edges[i] + edges[i+1]) / 2
+		widths[i] = edges[i+1] - edges[i]
+	}
+	heights := counts
+	if opts.Density && total > 0 {
+		for i := range heights {
+			heights[i] /= float64(total) * widths[i]
+		}
+	}
+	if opts.Cumulative {
+		sum := 0.0
+		for i := range heights {
+			if opts.Density {
+				// the running total of the areas
+				sum += heights[i] * widths[i]
+			} else {
+				sum += heights[i]
+			}
+			heights[i] = sum
+		}
+	}
+	return [][]float64{centers, heights, widths}, nil
+}
+
+// AddHistogram bins the values in Go and adds the histogram to the plot as
+// boxes. The boxes are filled with a light shade of their color and have a
+// border unless the options set another fill.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.AddHistogram("latency", latencies,
+//		glot.WithBinRule(glot.BinFreedmanDiaconis),
+//		glot.WithDensity(),
+//		glot.WithHistogramStyle(glot.WithColor("steelblue")))
+func (plot *plot) AddHistogram(name string, values any, opts ...HistogramOption) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	err := plot.checkNewName(name)
+	if err != nil {
+		return err
+	}
+	if plot.dimensions != 2 {
+		return &GnuplotError{err: "histograms can only be drawn on 2 dimensional plots"}
+	}
+	options := &HistogramOptions{}
+	for _, option := range opts {
+		option(options)
+	}
+	err = options.check()
+	if err != nil {
+		return err
+	}
+	columns, err := options.bin(values)
+	if err != nil {
+		return err
+	}
+
+	curve := &pointGroup{name: name, dimensions: 2, data: values, set: true, style: string(StyleBoxes)}
+	curve.options.Fill = &Fill{Density: 0.5, Border: true}
+	curve.options.apply(options.Style)
+	err = curve.options.check(plot.dimensions)
+	if err != nil {
+		return err
+	}
+	curve.histogram = options
+	curve.columns = columns
+	curve.using = "1:2:3"
+	return plot.addPointGroup(curve)
+}
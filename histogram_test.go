@@ -0,0 +1,163 @@
+package glot
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+// sequence returns the numbers from 1 to n.
+func sequence(n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = float64(i + 1)
+	}
+	return values
+}
+
+// spread returns n values evenly spread from 0 to high.
+func spread(n int, high float64) []float64 {
+	return linspace(0, high, n)
+}
+
+func TestBinCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		rule   BinRule
+		want   int
+	}{
+		{"sturges of 8 values", sequence(8), BinSturges, 4},
+		{"sturges of 100 values", sequence(100), BinSturges, 8},
+		{"sturges of 1000 values", sequence(1000), BinSturges, 11},
+		// IQR 49.5, width 2*49.5/100^(1/3) = 21.33 over a range of 99
+		{"freedman-diaconis of 100 values", sequence(100), BinFreedmanDiaconis, 5},
+		// IQR 499.5, width 2*499.5/10 = 99.9 over a range of 999
+		{"freedman-diaconis of 1000 values", sequence(1000), BinFreedmanDiaconis, 10},
+		{"freedman-diaconis without spread", []float64{1, 5, 5, 5, 5, 9}, BinFreedmanDiaconis, 4},
+		// a width of about 10 would make 1e8 bins
+		{"freedman-diaconis with an outlier", append(spread(1000, 99), 1e9), BinFreedmanDiaconis, 11},
+		{"freedman-diaconis of many values", spread(1_000_000, 1), BinFreedmanDiaconis, 100},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := binCount(test.values, test.rule); got != test.want {
+				t.Errorf("got %d bins, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHistogramEdges(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   HistogramOptions
+		values []float64
+		want   []float64
+	}{
+		{"bins", HistogramOptions{Bins: 4}, []float64{0, 3, 8}, []float64{0, 2, 4, 6, 8}},
+		{"width aligned on its multiples", HistogramOptions{Width: 2}, []float64{3, 7.5}, []float64{2, 4, 6, 8}},
+		{"explicit edges", HistogramOptions{Edges: []float64{0, 1, 10}}, []float64{3, 7.5}, []float64{0, 1, 10}},
+		{"single value", HistogramOptions{}, []float64{5, 5}, []float64{4.5, 5.5}},
+		{"rule", HistogramOptions{}, sequence(8), []float64{1, 2.75, 4.5, 6.25, 8}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.opts.edges(test.values)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("got edges %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHistogramBinLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		opts HistogramOptions
+	}{
+		{"tiny width", HistogramOptions{Width: 1e-9}},
+		{"too many bins", HistogramOptions{Bins: gMaxBins + 1}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.opts.check()
+			if err == nil {
+				_, err = test.opts.bin([]float64{0, 50, 100})
+			}
+			if err == nil {
+				t.Error("the bins were accepted")
+			}
+		})
+	}
+
+	opts := HistogramOptions{Width: 1e-3}
+	columns, err := opts.bin([]float64{0, 99.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(columns[0]) > gMaxBins {
+		t.Errorf("got %d bins", len(columns[0]))
+	}
+}
+
+func TestHistogramBin(t *testing.T) {
+	values := []float64{0.5, 1, 1.2, 2, 2.5, 2.5, 3.9, 4, 7, 9.5, math.NaN()}
+	tests := []struct {
+		name string
+		opts HistogramOptions
+		// the heights of the bars, or nil to skip the check
+		heights []float64
+		// the area of the bars when Density is set, else the last height
+		// when Cumulative is set
+		total float64
+	}{
+		{"counts", HistogramOptions{Edges: []float64{0, 1, 2, 10}}, []float64{1, 2, 7}, 0},
+		{"out of the edges", HistogramOptions{Edges: []float64{1, 2.5, 4}}, []float64{3, 4}, 0},
+		{"density", HistogramOptions{Density: true}, nil, 1},
+		{"density of uneven bins", HistogramOptions{Edges: []float64{0, 1, 2, 10}, Density: true}, []float64{0.1, 0.2, 0.7 / 8}, 1},
+		{"density of a freedman-diaconis histogram", HistogramOptions{Rule: BinFreedmanDiaconis, Density: true}, nil, 1},
+		{"cumulative counts", HistogramOptions{Bins: 3, Cumulative: true}, nil, 10},
+		{"cumulative density", HistogramOptions{Width: 3, Density: true, Cumulative: true}, nil, 1},
+		{"cumulative density of uneven bins", HistogramOptions{Edges: []float64{0, 1, 2, 10}, Density: true, Cumulative: true}, []float64{0.1, 0.3, 1}, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			columns, err := test.opts.bin(values)
+			if err != nil {
+				t.Fatal(err)
+			}
+			centers, heights, widths := columns[0], columns[1], columns[2]
+			if len(centers) != len(heights) || len(centers) != len(widths) {
+				t.Fatalf("got %d centers, %d heights and %d widths", len(centers), len(heights), len(widths))
+			}
+			if test.heights != nil && !floatsNear(heights, test.heights) {
+				t.Errorf("got heights %v, want %v", heights, test.heights)
+			}
+			switch {
+			case test.opts.Cumulative:
+				if last := heights[len(heights)-1]; math.Abs(last-test.total) > 1e-12 {
+					t.Errorf("the running total ends at %v, want %v", last, test.total)
+				}
+			case test.opts.Density:
+				area := 0.0
+				for i := range heights {
+					area += heights[i] * widths[i]
+				}
+				if math.Abs(area-test.total) > 1e-12 {
+					t.Errorf("the density integrates to %v, want %v", area, test.total)
+				}
+			}
+		})
+	}
+}
+
+// floatsNear reports whether the values are equal up to rounding errors.
+func floatsNear(got, want []float64) bool {
+	return slices.EqualFunc(got, want, func(a, b float64) bool {
+		return math.Abs(a-b) <= 1e-9*max(1, math.Abs(b))
+	})
+}
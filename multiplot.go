@@ -0,0 +1,548 @@
+package glot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// multiplotTitleSpace is the share of the image height kept for the title of
+// a multiplot whose panels fill the whole image.
+const multiplotTitleSpace = 0.05
+
+// Multiplot draws several plots as the panels of a single image. The panels
+// are laid out on a grid of rows and columns, filled row by row, unless they
+// are given their own position.
+//
+// The panels are regular plots. Every time the multiplot is saved or
+// rendered, their settings and point groups are drawn again by a gnuplot
+// process owned by the multiplot, so the plots can keep changing between
+// two images.
+//
+// Usage
+//
+//	cpu, _ := glot.NewPlot(2, false)
+//	cpu.AddPointGroup("cpu", glot.StyleLines, cpuUsage)
+//	memory, _ := glot.NewPlot(2, false)
+//	memory.AddPointGroup("memory", glot.StyleLines, memoryUsage)
+//
+//	multiplot, _ := glot.NewMultiplot(2, 1)
+//	defer multiplot.Close()
+//	multiplot.SetTitle("host-1")
+//	multiplot.SetSharedAxes(glot.AxisX)
+//	multiplot.AddPanel(cpu, glot.WithPanelTitle("CPU"))
+//	multiplot.AddPanel(memory, glot.WithPanelTitle("Memory"))
+//	multiplot.SavePlot("host-1.png", 800, 900)
+type Multiplot struct {
+	mu       sync.Mutex      // serializes the calls, guards all the fields below and the gnuplot pipes
+	ctx      context.Context // context bounding the lifetime of the gnuplot process
+	proc     *plotterProcess
+	rows     int
+	cols     int
+	title    string
+	terminal Terminal
+	margins  *Margins      // area covered by the grid, nil to fill the whole image
+	spacingX float64       // horizontal space between the grid cells when margins are set
+	spacingY float64       // vertical space between the grid cells when margins are set
+	shared   map[Axis]bool // axes shared by all the panels
+	panels   []*panel
+	nCells   int // number of grid cells taken by panels
+}
+
+// Margins places the edges of a plot area on the image, as fractions of the
+// image width and height measured from its bottom left corner. The tics and
+// the labels of the axes are drawn outside of the area.
+type Margins struct {
+	Left, Right, Bottom, Top float64
+}
+
+func (margins Margins) valid() bool {
+	return 0 <= margins.Left && margins.Left < margins.Right && margins.Right <= 1 &&
+		0 <= margins.Bottom && margins.Bottom < margins.Top && margins.Top <= 1
+}
+
+// commands returns the commands placing the plot area.
+func (margins Margins) commands() string {
+	return fmt.Sprintf("set lmargin at screen %s\nset rmargin at screen %s\nset bmargin at screen %s\nset tmargin at screen %s",
+		formatFloat(margins.Left), formatFloat(margins.Right),
+		formatFloat(margins.Bottom), formatFloat(margins.Top))
+}
+
+// panel is a plot drawn by a multiplot.
+type panel struct {
+	plot    *plot
+	title   string
+	cell    int        // index of the grid cell, -1 for panels placed by their options
+	margins *Margins   // plot area set by WithPanelMargins
+	origin  [2]float64 // bottom left corner set by WithPanelPosition
+	size    [2]float64 // width and height set by WithPanelPosition, zero if not set
+}
+
+// PanelOption changes how a plot is drawn as a panel of a Multiplot.
+type PanelOption func(*panel)
+
+// WithPanelTitle sets the title of the panel, replacing the title of the plot.
+func WithPanelTitle(title string) PanelOption {
+	return func(panel *panel) {
+		panel.title = title
+	}
+}
+
+// WithPanelPosition places the panel at the given position instead of the
+// next grid cell. The position and the size are fractions of the image width
+// and height measured from its bottom left corner.
+func WithPanelPosition(x, y, width, height float64) PanelOption {
+	return func(panel *panel) {
+		panel.origin = [2]float64{x, y}
+		panel.size = [2]float64{width, height}
+	}
+}
+
+// WithPanelMargins places the plot area of the panel instead of drawing it
+// in the next grid cell.
+func WithPanelMargins(margins Margins) PanelOption {
+	return func(panel *panel) {
+		panel.margins = &margins
+	}
+}
+
+// NewMultiplot makes a multiplot laying out its panels on a grid of the given
+// number of rows and columns.
+//
+// Usage
+//
+//	multiplot, _ := glot.NewMultiplot(3, 1)
+//	defer multiplot.Close()
+func NewMultiplot(rows, cols int) (*Multiplot, error) {
+	return NewMultiplotContext(context.Background(), rows, cols)
+}
+
+// NewMultiplotContext is like NewMultiplot but binds the gnuplot process to
+// ctx. When ctx is done the process is killed and every pending or later
+// call returns ctx.Err().
+func NewMultiplotContext(ctx context.Context, rows, cols int) (*Multiplot, error) {
+	err := initGnuplot()
+	if err != nil {
+		return nil, err
+	}
+	if rows < 1 || cols < 1 {
+		return nil, &GnuplotError{err: fmt.Sprintf("invalid layout %dx%d", rows, cols)}
+	}
+	proc, err := newPlotterProc(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	return &Multiplot{
+		ctx:      ctx,
+		proc:     proc,
+		rows:     rows,
+		cols:     cols,
+		terminal: PngOptions{},
+		shared:   make(map[Axis]bool),
+	}, nil
+}
+
+// AddPanel adds a plot made by NewPlot to the multiplot. The plot takes the
+// next cell of the grid unless it is placed by WithPanelPosition or
+// WithPanelMargins. The same plot may be added several times.
+//
+// Usage
+//
+//	multiplot.AddPanel(cpu, glot.WithPanelTitle("CPU"))
+//	multiplot.AddPanel(legend, glot.WithPanelPosition(0.8, 0.8, 0.2, 0.2))
+func (multiplot *Multiplot) AddPanel(p Plot, opts ...PanelOption) error {
+	multiplot.mu.Lock()
+	defer multiplot.mu.Unlock()
+
+	plot, ok := p.(*plot)
+	if !ok {
+		return &GnuplotError{err: fmt.Sprintf("a panel must be a plot made by NewPlot, got %T", p)}
+	}
+	panel := &panel{plot: plot, cell: -1}
+	for _, option := range opts {
+		option(panel)
+	}
+	if panel.margins != nil && !panel.margins.valid() {
+		return &GnuplotError{err: fmt.Sprintf("invalid panel margins %+v", *panel.margins)}
+	}
+	if panel.size[0] < 0 || panel.size[1] < 0 {
+		return &GnuplotError{err: fmt.Sprintf("invalid panel size %vx%v", panel.size[0], panel.size[1])}
+	}
+	if panel.margins == nil && panel.size == [2]float64{} {
+		if multiplot.nCells == multiplot.rows*multiplot.cols {
+			return &GnuplotError{err: fmt.Sprintf("all the cells of the %dx%d layout are taken", multiplot.rows, multiplot.cols)}
+		}
+		panel.cell = multiplot.nCells
+		multiplot.nCells++
+	}
+	multiplot.panels = append(multiplot.panels, panel)
+	return nil
+}
+
+// SetTitle sets the title drawn above all the panels.
+func (multiplot *Multiplot) SetTitle(title string) error {
+	multiplot.mu.Lock()
+	defer multiplot.mu.Unlock()
+	multiplot.title = title
+	return nil
+}
+
+// SetLayoutMargins makes the grid cover the area within the margins instead
+// of the whole image, with the given space between the plot areas of
+// neighbouring cells. With a zero spacing the panels touch each other, which
+// is how panels sharing an axis are usually drawn.
+//
+// Usage
+//
+//	multiplot.SetLayoutMargins(glot.Margins{Left: 0.1, Right: 0.95, Bottom: 0.1, Top: 0.9}, 0, 0)
+func (multiplot *Multiplot) SetLayoutMargins(margins Margins, spacingX, spacingY float64) error {
+	multiplot.mu.Lock()
+	defer multiplot.mu.Unlock()
+
+	if !margins.valid() {
+		return &GnuplotError{err: fmt.Sprintf("invalid layout margins %+v", margins)}
+	}
+	if spacingX < 0 || spacingY < 0 ||
+		float64(multiplot.cols-1)*spacingX >= margins.Right-margins.Left ||
+		float64(multiplot.rows-1)*spacingY >= margins.Top-margins.Bottom {
+		return &GnuplotError{err: fmt.Sprintf("invalid layout spacing %vx%v", spacingX, spacingY)}
+	}
+	multiplot.margins = &margins
+	multiplot.spacingX = spacingX
+	multiplot.spacingY = spacingY
+	return nil
+}
+
+// SetSharedAxes makes the panels share the x or the y axis: the axis gets the
+// same range in all the panels, covering the data of all of them, and only
+// the grid panels of the bottom row (for x) or of the left column (for y)
+// show its tic labels and its label.
+//
+// Usage
+//
+//	multiplot.SetSharedAxes(glot.AxisX)
+func (multiplot *Multiplot) SetSharedAxes(axes ...Axis) error {
+	multiplot.mu.Lock()
+	defer multiplot.mu.Unlock()
+
+	for _, axis := range axes {
+		if axis != AxisX && axis != AxisY {
+			return &GnuplotError{err: fmt.Sprintf("only the x and y axes can be shared, got '%s'", axis)}
+		}
+	}
+	for _, axis := range axes {
+		multiplot.shared[axis] = true
+	}
+	return nil
+}
+
+// SetFormat sets the format the multiplot is saved in, png by default.
+func (multiplot *Multiplot) SetFormat(format Format) error {
+	multiplot.mu.Lock()
+	defer multiplot.mu.Unlock()
+	multiplot.terminal = defaultTerminal(format)
+	return nil
+}
+
+// SetTerminal sets the format the multiplot is saved in together with the
+// terminal options.
+func (multiplot *Multiplot) SetTerminal(terminal Terminal) error {
+	multiplot.mu.Lock()
+	defer multiplot.mu.Unlock()
+
+	if terminal == nil {
+		return &GnuplotError{err: "terminal must not be nil"}
+	}
+	multiplot.terminal = terminal
+	return nil
+}
+
+// SavePlot draws all the panels into a single file. It blocks until gnuplot
+// has finished writing the file.
+//
+// Usage
+//
+//	multiplot.SavePlot("dashboard.png", 800, 900)
+func (multiplot *Multiplot) SavePlot(filename string, width, height int) error {
+	return multiplot.SavePlotContext(multiplot.ctx, filename, width, height)
+}
+
+// SavePlotContext is like SavePlot but gives up when ctx is done. In that
+// case the gnuplot process is killed, so the multiplot can't be used anymore.
+func (multiplot *Multiplot) SavePlotContext(ctx context.Context, filename string, width, height int) error {
+	multiplot.mu.Lock()
+	defer multiplot.mu.Unlock()
+
+	draw, err := multiplot.script(false)
+	if err != nil {
+		return err
+	}
+	err = multiplot.cmdContext(ctx, saveScript(multiplot.terminal.command(width, height), filename, draw))
+	if err != nil {
+		return err
+	}
+	return checkSavedFile(filename)
+}
+
+// Render writes all the panels encoded in the given format to w.
+func (multiplot *Multiplot) Render(w io.Writer, format Format, width, height int) error {
+	return multiplot.RenderContext(multiplot.ctx, w, format, width, height)
+}
+
+// RenderContext is like Render but gives up when ctx is done. In that case
+// the gnuplot process is killed, so the multiplot can't be used anymore.
+func (multiplot *Multiplot) RenderContext(ctx context.Context, w io.Writer, format Format, width, height int) error {
+	multiplot.mu.Lock()
+	defer multiplot.mu.Unlock()
+
+	draw, err := multiplot.script(true)
+	if err != nil {
+		return err
+	}
+	begin, end := multiplot.proc.stdout.expect()
+	err = multiplot.cmdContext(ctx, renderScript(matchTerminal(multiplot.terminal, format).command(width, height), begin, end, draw))
+	if err != nil {
+		multiplot.proc.stdout.cancel()
+		return err
+	}
+
+	frame, err := multiplot.proc.frameContext(ctx, begin, end)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(frame)
+	return err
+}
+
+// Bytes returns all the panels encoded in the given format.
+func (multiplot *Multiplot) Bytes(format Format, width, height int) ([]byte, error) {
+	var buf bytes.Buffer
+	err := multiplot.Render(&buf, format, width, height)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Close stops the gnuplot process of the multiplot. The plots of the panels
+// are not closed.
+func (multiplot *Multiplot) Close() error {
+	multiplot.mu.Lock()
+	defer multiplot.mu.Unlock()
+
+	multiplot.proc.stdin.Close()
+	multiplot.panels = nil
+	multiplot.nCells = 0
+	return multiplot.proc.handle.Wait()
+}
+
+// cmdContext runs a command, giving up when ctx is done.
+func (multiplot *Multiplot) cmdContext(ctx context.Context, command string) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+	err = multiplot.ctx.Err()
+	if err != nil {
+		return err
+	}
+	_, err = multiplot.proc.execContext(ctx, command)
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil && multiplot.ctx.Err() != nil {
+		return multiplot.ctx.Err()
+	}
+	return err
+}
+
+// script returns the commands drawing all the panels. Every panel starts from
+// the default settings, so the settings of a panel don't leak into the next
+// one.
+func (multiplot *Multiplot) script(rendering bool) (string, error) {
+	if len(multiplot.panels) == 0 {
+		return "", &GnuplotError{err: "This multiplot has 0 panels and therefore it can't be printed."}
+	}
+	ranges := multiplot.sharedRanges()
+
+	start := fmt.Sprintf("set multiplot layout %d,%d", multiplot.rows, multiplot.cols)
+	if multiplot.title != "" {
+		start += " title " + doubleQuote(multiplot.title)
+	}
+	commands := []string{start}
+	for i, panel := range multiplot.panels {
+		plot := panel.plot
+		plot.mu.Lock()
+		if len(plot.order) == 0 {
+			plot.mu.Unlock()
+			return "", &GnuplotError{err: fmt.Sprintf("panel %d has 0 curves and therefore it can't be printed.", i)}
+		}
+		commands = append(commands, "reset", multiplot.geometry(panel))
+		for _, setting := range plot.settings {
+			commands = append(commands, setting.command)
+		}
+		commands = append(commands, multiplot.sharing(panel)...)
+		commands = append(commands, ranges...)
+		if panel.title != "" {
+			commands = append(commands, "set title "+doubleQuote(panel.title))
+		}
+		var blocks []string
+		for _, name := range plot.order {
+			pointGroup := plot.pointGroup[name]
+			if pointGroup.block != "" {
+				commands = append(commands, dataBlock(pointGroup.block, pointGroup))
+				blocks = append(blocks, pointGroup.block)
+			}
+		}
+		commands = append(commands, plot.plotCommand())
+		for _, block := range blocks {
+			commands = append(commands, "undefine "+block)
+		}
+		plot.mu.Unlock()
+	}
+	commands = append(commands, "unset multiplot")
+	if rendering {
+		// reset may have sent the output of print back to stderr
+		commands = append(commands, "set print '-'")
+	}
+	return strings.Join(commands, "\n"), nil
+}
+
+// geometry returns the commands placing the panel on the image.
+func (multiplot *Multiplot) geometry(panel *panel) string {
+	if panel.margins != nil {
+		return panel.margins.commands()
+	}
+	if panel.cell < 0 {
+		return fmt.Sprintf("set origin %s,%s\nset size %s,%s",
+			formatFloat(panel.origin[0]), formatFloat(panel.origin[1]),
+			formatFloat(panel.size[0]), formatFloat(panel.size[1]))
+	}
+
+	row, col := panel.cell/multiplot.cols, panel.cell%multiplot.cols
+	if multiplot.margins != nil {
+		area := multiplot.margins
+		width := (area.Right - area.Left - float64(multiplot.cols-1)*multiplot.spacingX) / float64(multiplot.cols)
+		height := (area.Top - area.Bottom - float64(multiplot.rows-1)*multiplot.spacingY) / float64(multiplot.rows)
+		left := area.Left + float64(col)*(width+multiplot.spacingX)
+		top := area.Top - float64(row)*(height+multiplot.spacingY)
+		return Margins{Left: left, Right: left + width, Bottom: top - height, Top: top}.commands()
+	}
+
+	top := 1.0
+	if multiplot.title != "" {
+		top -= multiplotTitleSpace
+	}
+	width := 1 / float64(multiplot.cols)
+	height := top / float64(multiplot.rows)
+	return fmt.Sprintf("set origin %s,%s\nset size %s,%s",
+		formatFloat(float64(col)*width), formatFloat(top-float64(row+1)*height),
+		formatFloat(width), formatFloat(height))
+}
+
+// sharing returns the commands hiding the tic labels and the label of the
+// shared axes in the grid panels which are not on the bottom row or on the
+// left column.
+func (multiplot *Multiplot) sharing(panel *panel) []string {
+	if panel.cell < 0 {
+		return nil
+	}
+	var commands []string
+	if multiplot.shared[AxisX] && panel.cell+multiplot.cols < multiplot.nCells {
+		commands = append(commands, "set format x \"\"", "unset xlabel")
+	}
+	if multiplot.shared[AxisY] && panel.cell%multiplot.cols > 0 {
+		commands = append(commands, "set format y \"\"", "unset ylabel")
+	}
+	return commands
+}
+
+// sharedRanges returns the commands setting the range of the shared axes to
+// cover the data of all the panels.
+func (multiplot *Multiplot) sharedRanges() []string {
+	var commands []string
+	for _, axis := range []Axis{AxisX, AxisY} {
+		if !multiplot.shared[axis] {
+			continue
+		}
+		from, to := math.Inf(1), math.Inf(-1)
+		for _, panel := range multiplot.panels {
+			panel.plot.mu.Lock()
+			for _, name := range panel.plot.order {
+				low, high, ok := panel.plot.pointGroup[name].extent(axis)
+				if ok {
+					from, to = min(from, low), max(to, high)
+				}
+			}
+			panel.plot.mu.Unlock()
+		}
+		if from > to {
+			continue
+		}
+		r := Range{Min: At(from), Max: At(to)}
+		commands = append(commands, r.command(axis, time.UTC))
+	}
+	return commands
+}
+
+// extent returns the smallest and the largest finite values the point group
+// has along the primary x or y axis. ok is false if it has none.
+func (pointGroup *pointGroup) extent(axis Axis) (from, to float64, ok bool) {
+	axes := pointGroup.options.Axes
+	if axis == AxisX && (axes == AxesX2Y1 || axes == AxesX2Y2) ||
+		axis == AxisY && (axes == AxesX1Y2 || axes == AxesX2Y2) {
+		return 0, 0, false
+	}
+	columns := pointGroup.columns
+	if len(columns) == 0 {
+		return 0, 0, false
+	}
+	if pointGroup.bars != nil {
+		// the boxes of the bars of every series
+		from, to = math.Inf(1), math.Inf(-1)
+		for k := 0; k < len(columns); k += barColumns {
+			edges := columns[k+2 : k+4]
+			if axis == AxisY {
+				edges = columns[k+4 : k+6]
+			}
+			for _, column := range edges {
+				for _, value := range column {
+					from, to = min(from, value), max(to, value)
+				}
+			}
+		}
+		return from, to, from <= to
+	}
+	if pointGroup.heatmap != nil {
+		// the cells are drawn at the indices of the columns and the rows
+		if axis == AxisX {
+			return 0, float64(len(columns) - 1), true
+		}
+		return 0, float64(len(columns[0]) - 1), true
+	}
+	if len(columns) == 1 {
+		// a single column is drawn against the index of the points
+		if axis == AxisX {
+			n := len(columns[0])
+			return 0, float64(n - 1), n > 0
+		}
+		columns = [][]float64{nil, columns[0]}
+	}
+	column := columns[0]
+	if axis == AxisY {
+		column = columns[1]
+	}
+	from, to = math.Inf(1), math.Inf(-1)
+	for _, value := range column {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+		from, to = min(from, value), max(to, value)
+	}
+	return from, to, from <= to
+}
@@ -0,0 +1,423 @@
+package glot
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+)
+
+// Overlay is a curve computed in Go from the points of a point group, such as
+// a moving average or a regression line, see AddOverlay.
+type Overlay interface {
+	// curves computes the curves of the overlay from the points sorted by x.
+	curves(name string, xs, ys []float64) ([]overlayCurve, error)
+}
+
+// overlayCurve is one of the curves drawn by an overlay.
+type overlayCurve struct {
+	name    string
+	columns [][]float64
+	band    bool // the columns are x, ylow and yhigh of a filled band
+}
+
+type movingAverage struct {
+	window int
+}
+
+// MovingAverage averages every point with the points before it, up to
+// window points in total.
+func MovingAverage(window int) Overlay {
+	return movingAverage{window: window}
+}
+
+func (overlay movingAverage) curves(name string, xs, ys []float64) ([]overlayCurve, error) {
+	if overlay.window < 1 {
+		return nil, &GnuplotError{err: fmt.Sprintf("invalid moving average window %d", overlay.window)}
+	}
+	averages := make([]float64, len(ys))
+	sum := 0.0
+	for i, y := range ys {
+		sum += y
+		if i >= overlay.window {
+			sum -= ys[i-overlay.window]
+		}
+		averages[i] = sum / float64(min(i+1, overlay.window))
+	}
+	return []overlayCurve{{name: name, columns: [][]float64{xs, averages}}}, nil
+}
+
+type exponentialMovingAverage struct {
+	alpha float64
+}
+
+// ExponentialMovingAverage averages the points with weights decreasing
+// exponentially with their age. The larger alpha, from 0 excluded to 1, the
+// more the recent points weigh.
+func ExponentialMovingAverage(alpha float64) Overlay {
+	return exponentialMovingAverage{alpha: alpha}
+}
+
+func (overlay exponentialMovingAverage) curves(name string, xs, ys []float64) ([]overlayCurve, error) {
+	if !(overlay.alpha > 0 && overlay.alpha <= 1) {
+		return nil, &GnuplotError{err: fmt.Sprintf("invalid exponential moving average factor %v", overlay.alpha)}
+	}
+	averages := make([]float64, len(ys))
+	averages[0] = ys[0]
+	for i := 1; i < len(ys); i++ {
+		averages[i] = overlay.alpha*ys[i] + (1-overlay.alpha)*averages[i-1]
+	}
+	return []overlayCurve{{name: name, columns: [][]float64{xs, averages}}}, nil
+}
+
+type loess struct {
+	span float64
+}
+
+// LOESS fits a line to the neighbourhood of every point, weighting the
+// neighbours by their distance, and draws the fitted values. The span, from 0
+// excluded to 1, is the fraction of the points in every neighbourhood.
+func LOESS(span float64) Overlay {
+	return loess{span: span}
+}
+
+func (overlay loess) curves(name string, xs, ys []float64) ([]overlayCurve, error) {
+	if !(overlay.span > 0 && overlay.span <= 1) {
+		return nil, &GnuplotError{err: fmt.Sprintf("invalid LOESS span %v", overlay.span)}
+	}
+	n := len(xs)
+	k := min(n, max(2, int(math.Ceil(overlay.span*float64(n)))))
+	fitted := make([]float64, n)
+	low := 0 // first of the k nearest neighbours, which are contiguous since xs is sorted
+	for i, x := range xs {
+		for low+k < n && x-xs[low] > xs[low+k]-x {
+			low++
+		}
+		neighbours := xs[low : low+k]
+		radius := max(x-neighbours[0], neighbours[k-1]-x)
+
+		var sw, swx, swy, swxx, swxy float64
+		for j, xj := range neighbours {
+			w := 1.0
+			if radius > 0 {
+				d := math.Abs(xj-x) / radius
+				w = math.Pow(1-d*d*d, 3)
+			}
+			yj := ys[low+j]
+			sw += w
+			swx += w * xj
+			swy += w * yj
+			swxx += w * xj * xj
+			swxy += w * xj * yj
+		}
+		if sw == 0 {
+			// the neighbours are all on the edge of the neighbourhood
+			fitted[i] = ys[i]
+			continue
+		}
+		meanX, meanY := swx/sw, swy/sw
+		variance := swxx/sw - meanX*meanX
+		if variance <= 1e-12*(meanX*meanX+1) {
+			fitted[i] = meanY
+			continue
+		}
+		slope := (swxy/sw - meanX*meanY) / variance
+		fitted[i] = meanY + slope*(x-meanX)
+	}
+	return []overlayCurve{{name: name, columns: [][]float64{xs, fitted}}}, nil
+}
+
+type linearRegression struct {
+	confidence float64
+}
+
+// LinearRegression draws the least squares line of the points. With a
+// confidence level such as 0.95, the confidence band of the line is drawn
+// around it as well; no band is drawn with a level of 0.
+func LinearRegression(confidence float64) Overlay {
+	return linearRegression{confidence: confidence}
+}
+
+// gRegressionSamples is the number of points the regression line and its
+// confidence band are drawn with.
+const gRegressionSamples = 100
+
+func (overlay linearRegression) curves(name string, xs, ys []float64) ([]overlayCurve, error) {
+	if !(overlay.confidence >= 0 && overlay.confidence < 1) {
+		return nil, &GnuplotError{err: fmt.Sprintf("invalid confidence level %v", overlay.confidence)}
+	}
+	n := float64(len(xs))
+	if len(xs) < 3 {
+		return nil, &GnuplotError{err: "a linear regression needs at least 3 points"}
+	}
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= n
+	meanY /= n
+	var sxx, sxy float64
+	for i := range xs {
+		sxx += (xs[i] - meanX) * (xs[i] - meanX)
+		sxy += (xs[i] - meanX) * (ys[i] - meanY)
+	}
+	if sxx == 0 {
+		return nil, &GnuplotError{err: "a linear regression needs points with different x"}
+	}
+	slope := sxy / sxx
+	intercept := meanY - slope*meanX
+
+	lineX := linspace(xs[0], xs[len(xs)-1], gRegressionSamples)
+	lineY := make([]float64, len(lineX))
+	for i, x := range lineX {
+		lineY[i] = intercept + slope*x
+	}
+	line := overlayCurve{name: name, columns: [][]float64{lineX, lineY}}
+	if overlay.confidence == 0 {
+		return []overlayCurve{line}, nil
+	}
+
+	var sse float64
+	for i := range xs {
+		residual := ys[i] - intercept - slope*xs[i]
+		sse += residual * residual
+	}
+	deviation := math.Sqrt(sse / (n - 2))
+	t := studentQuantile(0.5+overlay.confidence/2, n-2)
+	lower := make([]float64, len(lineX))
+	upper := make([]float64, len(lineX))
+	for i, x := range lineX {
+		margin := t * deviation * math.Sqrt(1/n+(x-meanX)*(x-meanX)/sxx)
+		lower[i], upper[i] = lineY[i]-margin, lineY[i]+margin
+	}
+	band := overlayCurve{
+		name:    fmt.Sprintf("%s %.4g%% confidence", name, overlay.confidence*100),
+		columns: [][]float64{lineX, lower, upper},
+		band:    true,
+	}
+	return []overlayCurve{band, line}, nil
+}
+
+// studentQuantile returns the p-quantile of the Student's t distribution
+// with the given degrees of freedom, for p above 0.5.
+func studentQuantile(p, dof float64) float64 {
+	// cdf is the probability of a value below t > 0
+	cdf := func(t float64) float64 {
+		return 1 - 0.5*regularizedBeta(dof/(dof+t*t), dof/2, 0.5)
+	}
+	low, high := 0.0, 1.0
+	for cdf(high) < p {
+		low, high = high, 2*high
+	}
+	for range 100 {
+		middle := (low + high) / 2
+		if cdf(middle) < p {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+	return (low + high) / 2
+}
+
+// regularizedBeta returns the regularized incomplete beta function I_x(a, b),
+// evaluated with its continued fraction.
+func regularizedBeta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	if x > (a+1)/(a+b+2) {
+		// the continued fraction converges quickly on the other side
+		return 1 - regularizedBeta(1-x, b, a)
+	}
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab-lga-lgb+a*math.Log(x)+b*math.Log(1-x)) / a
+
+	// Lentz's algorithm
+	const tiny = 1e-300
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	fraction := d
+	for m := 1; m <= 200; m++ {
+		fm := float64(m)
+		for _, numerator := range []float64{
+			fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm)),
+			-(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1)),
+		} {
+			d = 1 + numerator*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + numerator/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			fraction *= d * c
+		}
+		if math.Abs(d*c-1) < 1e-15 {
+			break
+		}
+	}
+	return front * fraction
+}
+
+type percentileBands struct {
+	window      int
+	percentiles []float64
+}
+
+// PercentileBands draws the given percentiles, 50, 95 and 99 by default, of
+// every point and the points before it, up to window points in total. Every
+// percentile is a curve named after the overlay and the percentile, such as
+// "latency p95".
+func PercentileBands(window int, percentiles ...float64) Overlay {
+	if len(percentiles) == 0 {
+		percentiles = []float64{50, 95, 99}
+	}
+	return percentileBands{window: window, percentiles: slices.Clone(percentiles)}
+}
+
+func (overlay percentileBands) curves(name string, xs, ys []float64) ([]overlayCurve, error) {
+	if overlay.window < 1 {
+		return nil, &GnuplotError{err: fmt.Sprintf("invalid percentile window %d", overlay.window)}
+	}
+	for _, percentile := range overlay.percentiles {
+		if !(percentile >= 0 && percentile <= 100) {
+			return nil, &GnuplotError{err: fmt.Sprintf("invalid percentile %v", percentile)}
+		}
+	}
+	curves := make([]overlayCurve, len(overlay.percentiles))
+	for k, percentile := range overlay.percentiles {
+		curves[k] = overlayCurve{
+			name:    fmt.Sprintf("%s p%s", name, formatFloat(percentile)),
+			columns: [][]float64{xs, make([]float64, len(ys))},
+		}
+	}
+	sorted := make([]float64, 0, overlay.window)
+	for i, y := range ys {
+		// keep the values of the window sorted as it slides
+		if i >= overlay.window {
+			old := ys[i-overlay.window]
+			j := sort.SearchFloat64s(sorted, old)
+			sorted = slices.Delete(sorted, j, j+1)
+		}
+		j := sort.SearchFloat64s(sorted, y)
+		sorted = slices.Insert(sorted, j, y)
+		for k, percentile := range overlay.percentiles {
+			curves[k].columns[1][i] = quantile(sorted, percentile/100)
+		}
+	}
+	return curves, nil
+}
+
+// overlayPoints returns the finite points of the point group sorted by x.
+// The values of point groups of one column are drawn against their index.
+func (plot *plot) overlayPoints(pointGroup *pointGroup) ([]float64, []float64, error) {
+	if plot.dimensions != 2 || pointGroup.expression != "" || pointGroup.heatmap != nil || pointGroup.bars != nil {
+		return nil, nil, &GnuplotError{err: fmt.Sprintf("overlays can't be computed from the curve %s", pointGroup.name)}
+	}
+	columns := pointGroup.columns
+	if len(columns) == 1 {
+		columns = [][]float64{linspace(0, float64(len(columns[0])-1), len(columns[0])), columns[0]}
+	}
+	type point struct{ x, y float64 }
+	points := make([]point, 0, len(columns[0]))
+	for i, x := range columns[0] {
+		y := columns[1][i]
+		if math.IsNaN(x) || math.IsInf(x, 0) || math.IsNaN(y) || math.IsInf(y, 0) {
+			continue
+		}
+		points = append(points, point{x, y})
+	}
+	if len(points) == 0 {
+		return nil, nil, &GnuplotError{err: fmt.Sprintf("the curve %s has no finite points", pointGroup.name)}
+	}
+	slices.SortStableFunc(points, func(a, b point) int {
+		switch {
+		case a.x < b.x:
+			return -1
+		case a.x > b.x:
+			return 1
+		}
+		return 0
+	})
+	xs := make([]float64, len(points))
+	ys := make([]float64, len(points))
+	for i, p := range points {
+		xs[i], ys[i] = p.x, p.y
+	}
+	return xs, ys, nil
+}
+
+// AddOverlay computes an overlay from the points of a point group in Go and
+// adds its curves to the plot. The curves are drawn against the axes of the
+// point group, unless the options set others, and keep their values when the
+// point group changes. Overlays with several curves name them after the
+// overlay, such as "latency p95" for PercentileBands.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.AddPointGroup("latency", glot.StylePoints, [][]float64{times, latencies})
+//	plot.AddOverlay("trend", "latency", glot.LOESS(0.3), glot.WithColor("red"))
+//	plot.AddOverlay("fit", "latency", glot.LinearRegression(0.95))
+//	plot.AddOverlay("latency", "latency", glot.PercentileBands(100))
+func (plot *plot) AddOverlay(name, groupName string, overlay Overlay, opts ...PointGroupOption) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	source, exists := plot.pointGroup[groupName]
+	if !exists {
+		return &GnuplotError{err: fmt.Sprintf("A curve with name %s does not exist.", groupName)}
+	}
+	if overlay == nil {
+		return &GnuplotError{err: "the overlay must not be nil"}
+	}
+	xs, ys, err := plot.overlayPoints(source)
+	if err != nil {
+		return err
+	}
+	curves, err := overlay.curves(name, xs, ys)
+	if err != nil {
+		return err
+	}
+
+	added := make([]*pointGroup, 0, len(curves))
+	for _, c := range curves {
+		err = plot.checkNewName(c.name)
+		if err != nil {
+			return err
+		}
+		if slices.ContainsFunc(added, func(curve *pointGroup) bool { return curve.name == c.name }) {
+			return &GnuplotError{err: fmt.Sprintf("the overlay makes two curves named %s", c.name)}
+		}
+		curve := &pointGroup{name: c.name, dimensions: 2, data: c.columns, set: true, style: string(StyleLines)}
+		curve.options.Axes = source.options.Axes
+		if c.band {
+			// a light band behind the line
+			curve.style = string(StyleFilledCurves)
+			curve.options.Fill = &Fill{Density: 1}
+			curve.options.Transparency = 0.7
+		}
+		curve.options.apply(opts)
+		err = curve.options.check(plot.dimensions)
+		if err != nil {
+			return err
+		}
+		curve.columns = c.columns
+		curve.timeColumn = source.timeColumn
+		added = append(added, curve)
+	}
+
+	return plot.addPointGroup(added...)
+}
@@ -0,0 +1,137 @@
+package glot
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestStudentQuantile(t *testing.T) {
+	tests := []struct {
+		p, dof float64
+		want   float64
+	}{
+		{0.975, 1, 12.706},
+		{0.95, 5, 2.015},
+		{0.975, 10, 2.228},
+		{0.995, 30, 2.750},
+		{0.975, 1e6, 1.960},
+	}
+	for _, test := range tests {
+		if got := studentQuantile(test.p, test.dof); math.Abs(got-test.want) > 1e-3 {
+			t.Errorf("t(%v, %v) = %v, want %v", test.p, test.dof, got, test.want)
+		}
+	}
+}
+
+func TestRegularizedBeta(t *testing.T) {
+	tests := []struct {
+		x, a, b float64
+		want    float64
+	}{
+		{0, 2, 3, 0},
+		{1, 2, 3, 1},
+		{0.3, 1, 1, 0.3},     // the uniform distribution
+		{0.5, 2, 1, 0.25},    // x^a
+		{0.2, 1, 3, 0.488},   // 1-(1-x)^b
+		{0.5, 2.5, 2.5, 0.5}, // symmetric
+		{0.3, 2, 3, 0.3483},  // binomial sum
+		{0.9, 2, 3, 0.9963},  // the other side of the continued fraction
+	}
+	for _, test := range tests {
+		if got := regularizedBeta(test.x, test.a, test.b); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("I_%v(%v, %v) = %v, want %v", test.x, test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestLOESS(t *testing.T) {
+	xs := []float64{0, 0.5, 1, 3, 3.5, 6, 7, 10}
+	tests := []struct {
+		name string
+		span float64
+		ys   func(x float64) float64
+	}{
+		{"line", 0.5, func(x float64) float64 { return 2*x + 1 }},
+		{"line with the smallest span", 0.01, func(x float64) float64 { return -x }},
+		{"constant", 1, func(x float64) float64 { return 4 }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ys := make([]float64, len(xs))
+			for i, x := range xs {
+				ys[i] = test.ys(x)
+			}
+			curves, err := LOESS(test.span).curves("fit", xs, ys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(curves) != 1 || curves[0].name != "fit" || curves[0].band {
+				t.Fatalf("got curves %+v, want a single line named fit", curves)
+			}
+			if !slices.Equal(curves[0].columns[0], xs) {
+				t.Errorf("got x %v, want %v", curves[0].columns[0], xs)
+			}
+			if fitted := curves[0].columns[1]; !floatsNear(fitted, ys) {
+				t.Errorf("got fitted values %v, want %v", fitted, ys)
+			}
+		})
+	}
+
+	for _, span := range []float64{0, -0.5, 1.5, math.NaN()} {
+		_, err := LOESS(span).curves("fit", xs, xs)
+		if err == nil {
+			t.Errorf("the span %v was accepted", span)
+		}
+	}
+}
+
+func TestPercentileBands(t *testing.T) {
+	xs := []float64{1, 2, 3, 4, 5}
+	ys := []float64{5, 1, 4, 2, 3}
+	curves, err := PercentileBands(3, 0, 50, 100).curves("latency", xs, ys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		name   string
+		values []float64
+	}{
+		{"latency p0", []float64{5, 1, 1, 1, 2}},
+		{"latency p50", []float64{5, 3, 4, 2, 3}},
+		{"latency p100", []float64{5, 5, 5, 4, 4}},
+	}
+	if len(curves) != len(want) {
+		t.Fatalf("got %d curves, want %d", len(curves), len(want))
+	}
+	for i, curve := range curves {
+		if curve.name != want[i].name {
+			t.Errorf("curve %d is named %q, want %q", i, curve.name, want[i].name)
+		}
+		if !slices.Equal(curve.columns[0], xs) {
+			t.Errorf("%s: got x %v, want %v", curve.name, curve.columns[0], xs)
+		}
+		if !slices.Equal(curve.columns[1], want[i].values) {
+			t.Errorf("%s: got %v, want %v", curve.name, curve.columns[1], want[i].values)
+		}
+	}
+
+	curves, err = PercentileBands(10).curves("latency", xs, ys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(curves))
+	for i, curve := range curves {
+		names[i] = curve.name
+	}
+	if !slices.Equal(names, []string{"latency p50", "latency p95", "latency p99"}) {
+		t.Errorf("got the default curves %v", names)
+	}
+
+	for _, overlay := range []Overlay{PercentileBands(0), PercentileBands(3, 101), PercentileBands(3, -1), PercentileBands(3, math.NaN())} {
+		_, err := overlay.curves("latency", xs, ys)
+		if err == nil {
+			t.Errorf("%+v was accepted", overlay)
+		}
+	}
+}
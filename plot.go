@@ -1,43 +1,125 @@
 package glot
 
 import (
+	"bufio"
+	"encoding/binary"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"strconv"
+	"strings"
 )
 
-func (plot *plot) plotX(pointGroup *pointGroup) error {
-	f, err := os.CreateTemp(os.TempDir(), gGnuplotPrefix)
-	if err != nil {
-		return err
+// countPoints returns the number of rows the columns make up.
+// Extra values of the longer columns are ignored.
+func countPoints(columns ...[]float64) int {
+	npoints := len(columns[0])
+	for _, column := range columns[1:] {
+		npoints = min(npoints, len(column))
 	}
-	fname := f.Name()
-	plot.tmpFiles[fname] = f
-	for _, d := range pointGroup.castedData.([]float64) {
-		f.WriteString(fmt.Sprintf("%v\n", d))
-	}
-	f.Close()
-	cmd := plot.plotCmd
-	if plot.nPlots > 0 {
-		cmd = plotCommand
+	return npoints
+}
+
+// writeScans writes the columns as rows of space separated values, separating
+// every scanLength rows by a blank line as gnuplot expects for the scans of a
+// surface.
+// A scanLength of 0 writes a single scan.
+func writeScans(w io.Writer, scanLength int, columns ...[]float64) error {
+	npoints := countPoints(columns...)
+	buf := bufio.NewWriter(w)
+	for i := range npoints {
+		if scanLength > 0 && i > 0 && i%scanLength == 0 {
+			buf.WriteByte('\n')
+		}
+		for j, column := range columns {
+			if j > 0 {
+				buf.WriteByte(' ')
+			}
+			buf.WriteString(strconv.FormatFloat(column[i], 'g', -1, 64))
+		}
+		buf.WriteByte('\n')
 	}
-	if pointGroup.style == "" {
-		pointGroup.style = defaultStyle
+	return buf.Flush()
+}
+
+// writeBinaryColumns writes the columns as rows of little endian float64 values.
+func writeBinaryColumns(w io.Writer, columns ...[]float64) error {
+	npoints := countPoints(columns...)
+	buf := bufio.NewWriter(w)
+	row := make([]byte, 0, 8*len(columns))
+	for i := range npoints {
+		row = row[:0]
+		for _, column := range columns {
+			row = binary.LittleEndian.AppendUint64(row, math.Float64bits(column[i]))
+		}
+		buf.Write(row)
 	}
-	var line string
-	if pointGroup.name == "" {
-		line = fmt.Sprintf("%s \"%s\" with %s", cmd, fname, pointGroup.style)
-	} else {
-		line = fmt.Sprintf("%s \"%s\" title \"%s\" with %s",
-			cmd, fname, pointGroup.name, pointGroup.style)
+	return buf.Flush()
+}
+
+// binaryFormat returns the datafile modifiers describing a file written by
+// writeBinaryColumns.
+func binaryFormat(ncolumns int) string {
+	return "binary format='" + strings.Repeat("%float64", ncolumns) + "' endian=little"
+}
+
+// binaryArrayFormat returns the datafile modifiers describing a matrix of the
+// given size written by writeBinaryColumns.
+func binaryArrayFormat(ncolumns, nrows int) string {
+	return fmt.Sprintf("binary array=(%d,%d) format='%%float64' endian=little", ncolumns, nrows)
+}
+
+// useBinary reports whether the given number of points should be sent in
+// binary format.
+func (plot *plot) useBinary(points int) bool {
+	if plot.transport == TransportBinary {
+		return true
 	}
-	plot.nPlots++
-	return plot.cmd(line)
+	return plot.binaryThreshold > 0 && points >= plot.binaryThreshold
 }
 
-func (plot *plot) plotXY(pointGroup *pointGroup) error {
-	x := pointGroup.castedData.([][]float64)[0]
-	y := pointGroup.castedData.([][]float64)[1]
-	npoints := min(len(x), len(y))
+// dataBlock returns the command defining a data block holding the data of
+// the point group.
+func dataBlock(name string, pointGroup *pointGroup) string {
+	var block strings.Builder
+	block.WriteString(name + " << EOD\n")
+	writeScans(&block, pointGroup.scanLength, pointGroup.columns...)
+	block.WriteString("EOD")
+	return block.String()
+}
+
+// writeData sends the columns of the point group to gnuplot using the data
+// transport of the plot and remembers where gnuplot can find them.
+func (plot *plot) writeData(pointGroup *pointGroup) error {
+	if pointGroup.expression != "" {
+		// gnuplot computes the points of expressions itself
+		pointGroup.source = pointGroup.expression
+		return nil
+	}
+	columns := pointGroup.columns
+	inBinary := plot.useBinary(countPoints(columns...))
+	modifiers := ""
+	if pointGroup.heatmap != nil {
+		// the cells of a heatmap are points as well
+		inBinary = plot.useBinary(len(columns)*len(columns[0])) && pointGroup.heatmap.LabelFormat == ""
+		modifiers = " matrix"
+	}
+	if pointGroup.scanLength > 0 {
+		// binary files don't keep the scans of surfaces apart
+		inBinary = false
+	}
+	if plot.transport == TransportDataBlock && !inBinary {
+		plot.nBlocks++
+		name := "$" + gBlockPrefix + strconv.Itoa(plot.nBlocks)
+		err := plot.cmd(dataBlock(name, pointGroup))
+		if err != nil {
+			return err
+		}
+		pointGroup.block = name
+		pointGroup.source = name + modifiers
+		return nil
+	}
 
 	f, err := os.CreateTemp(os.TempDir(), gGnuplotPrefix)
 	if err != nil {
@@ -45,61 +127,106 @@ func (plot *plot) plotXY(pointGroup *pointGroup) error {
 	}
 	fname := f.Name()
 	plot.tmpFiles[fname] = f
-
-	for i := range npoints {
-		f.WriteString(fmt.Sprintf("%v %v\n", x[i], y[i]))
+	pointGroup.file = fname
+	if inBinary {
+		err = writeBinaryColumns(f, columns...)
+	} else {
+		err = writeScans(f, pointGroup.scanLength, columns...)
 	}
-
 	f.Close()
-	cmd := plot.plotCmd
-	if plot.nPlots > 0 {
-		cmd = plotCommand
+	if err != nil {
+		return err
+	}
+	pointGroup.source = quote(fname)
+	switch {
+	case inBinary && pointGroup.heatmap != nil:
+		pointGroup.source += " " + binaryArrayFormat(len(columns), len(columns[0]))
+	case inBinary:
+		pointGroup.source += " " + binaryFormat(len(columns))
+	default:
+		pointGroup.source += modifiers
 	}
+	return nil
+}
 
-	if pointGroup.style == "" {
-		pointGroup.style = "points"
+// removeData removes the data of the point group from the disk and from gnuplot.
+func (plot *plot) removeData(pointGroup *pointGroup) error {
+	if pointGroup.file != "" {
+		if f, ok := plot.tmpFiles[pointGroup.file]; ok {
+			f.Close()
+			delete(plot.tmpFiles, pointGroup.file)
+		}
+		os.Remove(pointGroup.file)
+		pointGroup.file = ""
 	}
-	var line string
-	if pointGroup.name == "" {
-		line = fmt.Sprintf("%s \"%s\" with %s", cmd, fname, pointGroup.style)
-	} else {
-		line = fmt.Sprintf("%s \"%s\" title \"%s\" with %s",
-			cmd, fname, pointGroup.name, pointGroup.style)
+	if pointGroup.block != "" {
+		block := pointGroup.block
+		pointGroup.block = ""
+		return plot.cmd("undefine " + block)
 	}
-	plot.nPlots++
-	return plot.cmd(line)
+	return nil
 }
 
-func (plot *plot) plotXYZ(points *pointGroup) error {
-	x := points.castedData.([][]float64)[0]
-	y := points.castedData.([][]float64)[1]
-	z := points.castedData.([][]float64)[2]
-	npoints := min(len(x), len(y))
-	npoints = min(npoints, len(z))
-	f, err := os.CreateTemp(os.TempDir(), gGnuplotPrefix)
-	if err != nil {
-		return err
+// plotElement returns the part of the plot command drawing the point group.
+func (pointGroup *pointGroup) plotElement() string {
+	if pointGroup.heatmap != nil {
+		return pointGroup.heatmapElement()
 	}
-	fname := f.Name()
-	plot.tmpFiles[fname] = f
-
-	for i := range npoints {
-		f.WriteString(fmt.Sprintf("%v %v %v\n", x[i], y[i], z[i]))
+	if pointGroup.bars != nil {
+		return pointGroup.barChartElement()
+	}
+	style := pointGroup.style
+	if style == "" {
+		style = defaultStyle
+	}
+	element := pointGroup.source
+	if pointGroup.timeColumn {
+		// An expression makes gnuplot read the number as is instead of
+		// parsing it with the timefmt of the axis.
+		using := "($1)"
+		for i := 2; i <= len(pointGroup.columns); i++ {
+			using += ":" + strconv.Itoa(i)
+		}
+		element += " using " + using
+	} else if pointGroup.using != "" {
+		element += " using " + pointGroup.using
+	} else if len(pointGroup.columns) == 1 && pointGroup.options.Smooth.distribution() {
+		element += " using 1:(1)"
+	}
+	options := &pointGroup.options
+	if options.Smooth != "" {
+		element += " smooth " + string(options.Smooth)
+	}
+	if options.Axes != "" {
+		element += " axes " + string(options.Axes)
+	}
+	if pointGroup.name == "" {
+		element += " notitle"
+	} else {
+		element += " title " + doubleQuote(pointGroup.name)
 	}
+	element += " with " + style
+	if clause := options.styleClause(); clause != "" {
+		element += " " + clause
+	}
+	return element
+}
 
-	f.Close()
-	cmd := "splot" // Force 3D plot
-	if plot.nPlots > 0 {
-		cmd = plotCommand
+// replot rebuilds the plot command from all the point groups of the plot in
+// their order and sends it to gnuplot. Every change of the point groups goes
+// through replot, so the drawn plot always matches them.
+func (plot *plot) replot() error {
+	if len(plot.order) == 0 {
+		return nil
 	}
+	return plot.cmd(plot.plotCommand())
+}
 
-	var line string
-	if points.name == "" {
-		line = fmt.Sprintf("%s \"%s\" with %s", cmd, fname, points.style)
-	} else {
-		line = fmt.Sprintf("%s \"%s\" title \"%s\" with %s",
-			cmd, fname, points.name, points.style)
+// plotCommand returns the command drawing all the point groups of the plot.
+func (plot *plot) plotCommand() string {
+	elements := make([]string, len(plot.order))
+	for i, name := range plot.order {
+		elements[i] = plot.pointGroup[name].plotElement()
 	}
-	plot.nPlots++
-	return plot.cmd(line)
+	return plot.plotCmd + " " + strings.Join(elements, ", ")
 }
@@ -0,0 +1,150 @@
+package glot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// decodeRows reads the rows of little endian float64 values of ncolumns
+// columns described by a binary format.
+func decodeRows(t *testing.T, data []byte, ncolumns int) [][]float64 {
+	t.Helper()
+	if len(data)%(8*ncolumns) != 0 {
+		t.Fatalf("%d bytes are not rows of %d float64 values", len(data), ncolumns)
+	}
+	var rows [][]float64
+	for len(data) > 0 {
+		row := make([]float64, ncolumns)
+		for j := range row {
+			row[j] = math.Float64frombits(binary.LittleEndian.Uint64(data))
+			data = data[8:]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestWriteBinaryColumnsMatchesBinaryFormat(t *testing.T) {
+	columns := [][]float64{
+		{1, 2, 3},
+		{-1.5, math.Inf(1), 1e300},
+		{0, math.SmallestNonzeroFloat64, -0.25},
+	}
+	var buf bytes.Buffer
+	err := writeBinaryColumns(&buf, columns...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	format := binaryFormat(len(columns))
+	ncolumns := strings.Count(format, "%float64")
+	if ncolumns != len(columns) {
+		t.Fatalf("%s declares %d columns instead of %d", format, ncolumns, len(columns))
+	}
+	if !strings.Contains(format, "endian=little") {
+		t.Fatalf("%s doesn't declare little endian values", format)
+	}
+	rows := decodeRows(t, buf.Bytes(), ncolumns)
+	if len(rows) != len(columns[0]) {
+		t.Fatalf("got %d rows instead of %d", len(rows), len(columns[0]))
+	}
+	for i, row := range rows {
+		for j, value := range row {
+			if value != columns[j][i] {
+				t.Errorf("row %d column %d: got %v, want %v", i, j, value, columns[j][i])
+			}
+		}
+	}
+}
+
+func TestWriteBinaryColumnsMatchesBinaryArrayFormat(t *testing.T) {
+	matrix := [][]float64{
+		{1, 2, 3, 4},
+		{5, 6, 7, 8},
+		{9, 10, 11, 12},
+	}
+	columns, err := matrixColumns(matrix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = writeBinaryColumns(&buf, columns...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	format := binaryArrayFormat(len(columns), len(columns[0]))
+	match := regexp.MustCompile(`array=\((\d+),(\d+)\)`).FindStringSubmatch(format)
+	if match == nil {
+		t.Fatalf("%s declares no array size", format)
+	}
+	var width, height int
+	fmt.Sscan(match[1], &width)
+	fmt.Sscan(match[2], &height)
+	if width != len(matrix[0]) || height != len(matrix) {
+		t.Fatalf("%s declares a %dx%d array instead of %dx%d", format, width, height, len(matrix[0]), len(matrix))
+	}
+	if strings.Count(format, "%float64") != 1 || !strings.Contains(format, "endian=little") {
+		t.Fatalf("%s doesn't declare single little endian float64 values", format)
+	}
+	// gnuplot reads the values of an array with x varying fastest
+	values := decodeRows(t, buf.Bytes(), 1)
+	if len(values) != width*height {
+		t.Fatalf("got %d values instead of %d", len(values), width*height)
+	}
+	for y := range height {
+		for x := range width {
+			if got := values[y*width+x][0]; got != matrix[y][x] {
+				t.Errorf("cell (%d, %d): got %v, want %v", x, y, got, matrix[y][x])
+			}
+		}
+	}
+}
+
+// benchmarkColumns returns 2 columns of a few million points.
+func benchmarkColumns() [][]float64 {
+	const npoints = 3_000_000
+	xs := make([]float64, npoints)
+	ys := make([]float64, npoints)
+	for i := range xs {
+		xs[i] = float64(i) / 1000
+		ys[i] = math.Sin(xs[i])
+	}
+	return [][]float64{xs, ys}
+}
+
+func BenchmarkWriteScans(b *testing.B) {
+	columns := benchmarkColumns()
+	b.SetBytes(int64(16 * len(columns[0])))
+	for b.Loop() {
+		err := writeScans(io.Discard, 0, columns...)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteBinaryColumns(b *testing.B) {
+	columns := benchmarkColumns()
+	b.SetBytes(int64(16 * len(columns[0])))
+	for b.Loop() {
+		err := writeBinaryColumns(io.Discard, columns...)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestPlotElementQuotesTitle(t *testing.T) {
+	curve := &pointGroup{name: `say "hi" \ there`, source: "$data", style: string(StyleLines)}
+	want := `$data title "say \"hi\" \\ there" with lines`
+	if got := curve.plotElement(); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -2,25 +2,41 @@ package glot
 
 import (
 	"fmt"
+	"slices"
 )
 
 // A pointGroup refers to a set of points that need to plotted.
-// It could either be a set of points or a function of co-ordinates.
-// For Example z = Function(x,y)(3 Dimensional) or  y = Function(x) (2-Dimensional)
+// It could either be a set of points or a function of co-ordinates sampled in Go.
+// For Example z = Function(x,y)(3 Dimensional, see AddSurfaceFunc) or  y = Function(x) (2-Dimensional, see AddFunc)
 type pointGroup struct {
-	name       string // Name of the curve
-	dimensions int    // dimensions of the curve
-	style      string // current plotting style
-	data       any    // Data inside the curve in any integer/float format
-	castedData any    // The data inside the curve typecasted to float64
-	set        bool   //
+	name       string            // Name of the curve
+	dimensions int               // dimensions of the curve
+	style      string            // current plotting style
+	data       any               // Data inside the curve in any integer/float format
+	columns    [][]float64       // The data inside the curve typecasted to float64, one slice per coordinate
+	timeColumn bool              // the first column holds times in seconds since the Unix epoch
+	options    PointGroupOptions // how the curve is drawn
+	heatmap    *HeatmapOptions   // options of a heatmap, nil for the other curves
+	scanLength int               // number of points of every scan of a surface, 0 for the other curves
+	histogram  *HistogramOptions // binning of a histogram, nil for the other curves
+	bars       *barChart         // series of a bar chart, nil for the other curves
+	using      string            // columns drawn by the plot command, all of them in order if empty
+	expression string            // gnuplot expression drawn instead of data, empty for the other curves
+	source     string            // The file name or the data block holding the data in gnuplot
+	file       string            // temporary file holding the data
+	block      string            // data block holding the data
+	set        bool              //
 }
 
-type number interface {
-	int | int8 | int16 | int32 | int64 | float32 | float64
+// Number is the constraint of the numbers the generic functions such as
+// AddSeries accept.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
 }
 
-func toFloat64[T number](data []T) []float64 {
+func toFloat64[T Number](data []T) []float64 {
 	result := make([]float64, len(data))
 	for i, val := range data {
 		result[i] = float64(val)
@@ -28,7 +44,7 @@ func toFloat64[T number](data []T) []float64 {
 	return result
 }
 
-func to2DFloat64[T number](data [][]T) [][]float64 {
+func to2DFloat64[T Number](data [][]T) [][]float64 {
 	result := make([][]float64, len(data))
 	for i, val := range data {
 		result[i] = toFloat64(val)
@@ -36,33 +52,169 @@ func to2DFloat64[T number](data [][]T) [][]float64 {
 	return result
 }
 
-func (plot *plot) addMultiDimensionPointGroup(name string, style Style, points [][]float64) error {
-	if plot.dimensions != len(points) {
-		return &gnuplotError{fmt.Sprintf("The dimensions of this PointGroup are not compatible with the dimensions of the plot.\nIf you want to make a 2-d curve you must specify a 2-d plot.")}
+// pairsToColumns converts points given as pairs of coordinates to x and y
+// columns.
+func pairsToColumns[T Number](points [][2]T) [][]float64 {
+	columns := [][]float64{make([]float64, len(points)), make([]float64, len(points))}
+	for i, point := range points {
+		columns[0][i], columns[1][i] = float64(point[0]), float64(point[1])
 	}
+	return columns
+}
 
-	curve := &pointGroup{name: name, dimensions: plot.dimensions, data: points, set: true, style: string(style)}
+// triplesToColumns converts points given as triples of coordinates to x, y
+// and z columns.
+func triplesToColumns[T Number](points [][3]T) [][]float64 {
+	columns := [][]float64{make([]float64, len(points)), make([]float64, len(points)), make([]float64, len(points))}
+	for i, point := range points {
+		columns[0][i], columns[1][i], columns[2][i] = float64(point[0]), float64(point[1]), float64(point[2])
+	}
+	return columns
+}
 
-	curve.castedData = points
-	if plot.dimensions == 2 {
-		plot.plotXY(curve)
-	} else {
-		plot.plotXYZ(curve)
+// castData converts the data of a point group to float64 columns, one slice
+// per coordinate.
+func castData(data any) ([][]float64, error) {
+	switch v := data.(type) {
+	case columnData:
+		return v.columns()
+	case [][]float64:
+		return v, nil
+	case [][]float32:
+		return to2DFloat64(v), nil
+	case [][]int:
+		return to2DFloat64(v), nil
+	case [][]int8:
+		return to2DFloat64(v), nil
+	case [][]int16:
+		return to2DFloat64(v), nil
+	case [][]int32:
+		return to2DFloat64(v), nil
+	case [][]int64:
+		return to2DFloat64(v), nil
+	case [][]uint:
+		return to2DFloat64(v), nil
+	case [][]uint8:
+		return to2DFloat64(v), nil
+	case [][]uint16:
+		return to2DFloat64(v), nil
+	case [][]uint32:
+		return to2DFloat64(v), nil
+	case [][]uint64:
+		return to2DFloat64(v), nil
+	case [][2]float64:
+		return pairsToColumns(v), nil
+	case [][2]float32:
+		return pairsToColumns(v), nil
+	case [][2]int:
+		return pairsToColumns(v), nil
+	case [][2]int64:
+		return pairsToColumns(v), nil
+	case [][3]float64:
+		return triplesToColumns(v), nil
+	case [][3]float32:
+		return triplesToColumns(v), nil
+	case [][3]int:
+		return triplesToColumns(v), nil
+	case [][3]int64:
+		return triplesToColumns(v), nil
+	case []float64:
+		return [][]float64{v}, nil
+	case []float32:
+		return [][]float64{toFloat64(v)}, nil
+	case []int:
+		return [][]float64{toFloat64(v)}, nil
+	case []int8:
+		return [][]float64{toFloat64(v)}, nil
+	case []int16:
+		return [][]float64{toFloat64(v)}, nil
+	case []int32:
+		return [][]float64{toFloat64(v)}, nil
+	case []int64:
+		return [][]float64{toFloat64(v)}, nil
+	case []uint:
+		return [][]float64{toFloat64(v)}, nil
+	case []uint8:
+		return [][]float64{toFloat64(v)}, nil
+	case []uint16:
+		return [][]float64{toFloat64(v)}, nil
+	case []uint32:
+		return [][]float64{toFloat64(v)}, nil
+	case []uint64:
+		return [][]float64{toFloat64(v)}, nil
+	default:
+		return nil, &GnuplotError{err: fmt.Sprintf("unsupported data type %T, use slices of numbers or the generic functions such as AddSeries", data)}
 	}
-	plot.pointGroup[name] = curve
+}
 
+// checkColumns makes sure the columns can be drawn on the plot with the
+// style. The styles drawing error bars, boxes and financial data need their
+// own numbers of columns on 2 dimensional plots. Otherwise one dimensional
+// point groups fit any plot and the others must have the dimensions of the
+// plot.
+func (plot *plot) checkColumns(columns [][]float64, style Style) error {
+	counts, ok := gStyleColumns[style]
+	if ok && plot.dimensions == 2 {
+		if !slices.Contains(counts, len(columns)) {
+			return &GnuplotError{err: fmt.Sprintf("the style %s needs %s columns, got %d", style, formatCounts(counts), len(columns))}
+		}
+		return nil
+	}
+	if len(columns) != 1 && plot.dimensions != len(columns) {
+		return &GnuplotError{err: fmt.Sprintf("The dimensions of this PointGroup are not compatible with the dimensions of the plot.\nIf you want to make a 2-d curve you must specify a 2-d plot.")}
+	}
+	return nil
+}
+
+// checkNewName returns an error when the plot already has a point group with
+// the name.
+func (plot *plot) checkNewName(name string) error {
+	_, exists := plot.pointGroup[name]
+	if exists {
+		return &GnuplotError{err: fmt.Sprintf("A PointGroup with the name %s already exists, please use another name of the curve or remove this curve before using another one with the same name.", name)}
+	}
 	return nil
 }
 
-func (plot *plot) addOnewDimensionPointGroup(name string, style Style, points []float64) error {
-	curve := &pointGroup{name: name, dimensions: plot.dimensions, data: points, set: true, style: string(style)}
-	curve.castedData = points
-	plot.plotX(curve)
-	plot.pointGroup[name] = curve
+// addPointGroup sends the data of the curves to gnuplot and adds them to the
+// end of the plot. Nothing is added when gnuplot rejects the data or the new
+// plot command, for example because of an unknown style.
+func (plot *plot) addPointGroup(curves ...*pointGroup) error {
+	for i, curve := range curves {
+		err := plot.writeData(curve)
+		if err != nil {
+			for _, written := range curves[:i+1] {
+				plot.removeData(written)
+			}
+			return err
+		}
+	}
+	order := plot.order
+	for _, curve := range curves {
+		plot.pointGroup[curve.name] = curve
+		plot.order = append(plot.order, curve.name)
+	}
+	err := plot.replot()
+	if err != nil {
+		plot.order = order
+		for _, curve := range curves {
+			delete(plot.pointGroup, curve.name)
+			plot.removeData(curve)
+		}
+		return err
+	}
 	return nil
 }
 
 // AddPointGroup function adds a group of points to a plot.
+// The point groups are drawn in the order they were added.
+// The options set the color, the line width and the other drawing details.
+// Besides slices of numbers, the data can be one of the typed groups such as
+// YErrorBars or Candlesticks, which are drawn with their own style when the
+// style is empty. The number of columns is checked against the style.
+// Points can also be given as [][2]T or [][3]T slices of coordinates; the
+// generic functions such as AddSeries check the type of the data at compile
+// time.
 //
 // Usage
 //
@@ -71,48 +223,213 @@ func (plot *plot) addOnewDimensionPointGroup(name string, style Style, points []
 //	debug := false
 //	plot, _ := glot.NewPlot(dimensions, persist, debug)
 //	plot.AddPointGroup("Sample1", "points", []int32{51, 8, 4, 11})
-//	plot.AddPointGroup("Sample2", "points", []int32{1, 2, 4, 11})
+//	plot.AddPointGroup("Sample2", "lines", []int32{1, 2, 4, 11},
+//		glot.WithColor("#ff0000"), glot.WithLineWidth(2), glot.WithDashType(2))
 //	plot.SavePlot("1.png")
-func (plot *plot) AddPointGroup(name string, style Style, data any) (err error) {
-	_, exists := plot.pointGroup[name]
-	if exists {
-		return &gnuplotError{fmt.Sprintf("A PointGroup with the name %s already exists, please use another name of the curve or remove this curve before using another one with the same name.", name)}
+func (plot *plot) AddPointGroup(name string, style Style, data any, opts ...PointGroupOption) (err error) {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	err = plot.checkNewName(name)
+	if err != nil {
+		return err
 	}
 
-	switch v := data.(type) {
-	case [][]float64:
-		plot.addMultiDimensionPointGroup(name, style, v)
-	case [][]float32:
-		plot.addMultiDimensionPointGroup(name, style, to2DFloat64(v))
-	case [][]int:
-		plot.addMultiDimensionPointGroup(name, style, to2DFloat64(v))
-	case [][]int8:
-		plot.addMultiDimensionPointGroup(name, style, to2DFloat64(v))
-	case [][]int16:
-		plot.addMultiDimensionPointGroup(name, style, to2DFloat64(v))
-	case [][]int32:
-		plot.addMultiDimensionPointGroup(name, style, to2DFloat64(v))
-	case [][]int64:
-		plot.addMultiDimensionPointGroup(name, style, to2DFloat64(v))
-	case []float64:
-		plot.addOnewDimensionPointGroup(name, style, v)
-	case []float32:
-		plot.addOnewDimensionPointGroup(name, style, toFloat64(v))
-	case []int:
-		plot.addOnewDimensionPointGroup(name, style, toFloat64(v))
-	case []int8:
-		plot.addOnewDimensionPointGroup(name, style, toFloat64(v))
-	case []int16:
-		plot.addOnewDimensionPointGroup(name, style, toFloat64(v))
-	case []int32:
-		plot.addOnewDimensionPointGroup(name, style, toFloat64(v))
-	case []int64:
-		plot.addOnewDimensionPointGroup(name, style, toFloat64(v))
-	default:
-		return &gnuplotError{fmt.Sprintf("invalid number of dims ")}
+	columns, err := castData(data)
+	if err != nil {
+		return err
+	}
+	if style == "" {
+		if typed, ok := data.(columnData); ok {
+			style = typed.defaultStyle()
+		}
+	}
+	err = plot.checkColumns(columns, style)
+	if err != nil {
+		return err
+	}
+	curve := &pointGroup{name: name, dimensions: len(columns), data: data, set: true, style: string(style)}
+	curve.options.apply(opts)
+	err = curve.options.check(plot.dimensions)
+	if err != nil {
+		return err
+	}
+	curve.columns = columns
+	return plot.addPointGroup(curve)
+}
+
+// convertData converts new data of the point group to float64 columns the way
+// the point group was made. It returns the length of the scans of surfaces too.
+func (plot *plot) convertData(pointGroup *pointGroup, data any) ([][]float64, int, error) {
+	switch {
+	case pointGroup.timeColumn:
+		return nil, 0, &GnuplotError{err: fmt.Sprintf("the time series %s must be updated with UpdateTimeSeries", pointGroup.name)}
+	case pointGroup.expression != "":
+		return nil, 0, &GnuplotError{err: fmt.Sprintf("the curve %s is drawn from an expression and has no data", pointGroup.name)}
+	case pointGroup.heatmap != nil:
+		columns, err := matrixColumns(data)
+		return columns, 0, err
+	case pointGroup.histogram != nil:
+		columns, err := pointGroup.histogram.bin(data)
+		return columns, 0, err
+	case pointGroup.bars != nil:
+		series, ok := data.([]BarSeries)
+		if !ok {
+			return nil, 0, &GnuplotError{err: fmt.Sprintf("the data of the bar chart %s must be a []BarSeries", pointGroup.name)}
+		}
+		if len(series) == 0 {
+			return nil, 0, &GnuplotError{err: "a bar chart needs at least one series"}
+		}
+		columns, err := pointGroup.bars.columns(series)
+		if err != nil {
+			return nil, 0, err
+		}
+		return columns, 0, pointGroup.bars.setSeries(series, plot.dimensions)
+	case pointGroup.scanLength > 0:
+		grid, ok := data.(Grid)
+		if !ok {
+			return nil, 0, &GnuplotError{err: fmt.Sprintf("the data of the surface %s must be a Grid", pointGroup.name)}
+		}
+		return grid.columns()
+	}
+	columns, err := castData(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	return columns, 0, plot.checkColumns(columns, Style(pointGroup.style))
+}
+
+// UpdatePointGroup replaces the data of a point group keeping its name,
+// style and position in the plot.
+// The data of a heatmap is replaced by a new matrix, the data of a surface by
+// a new Grid, the data of a bar chart by new []BarSeries over the same
+// categories and the values of a histogram are binned again. Time series are
+// updated by UpdateTimeSeries.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.AddPointGroup("Sample1", "lines", []int32{51, 8, 4, 11})
+//	plot.UpdatePointGroup("Sample1", []int32{50, 9, 3, 12})
+func (plot *plot) UpdatePointGroup(name string, data any) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	pointGroup, exists := plot.pointGroup[name]
+	if !exists {
+		return &GnuplotError{err: fmt.Sprintf("A curve with name %s does not exist.", name)}
+	}
+	columns, scanLength, err := plot.convertData(pointGroup, data)
+	if err != nil {
+		return err
+	}
+	return plot.replaceData(pointGroup, data, columns, scanLength)
+}
+
+// replaceData replaces the data of the point group and draws the plot again.
+// The old data is kept until gnuplot accepts the new one.
+func (plot *plot) replaceData(pointGroup *pointGroup, data any, columns [][]float64, scanLength int) error {
+	updated := *pointGroup
+	updated.data = data
+	updated.dimensions = len(columns)
+	updated.columns = columns
+	updated.scanLength = scanLength
+	updated.file, updated.block = "", ""
+	err := plot.writeData(&updated)
+	if err != nil {
+		plot.removeData(&updated)
+		return err
+	}
+	old := *pointGroup
+	*pointGroup = updated
+	err = plot.replot()
+	if err != nil {
+		plot.removeData(pointGroup)
+		*pointGroup = old
+		return err
+	}
+	return plot.removeData(&old)
+}
+
+// RenamePointGroup changes the name of a point group, which is also its
+// title in the plot.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.AddPointGroup("Sample1", "lines", []int32{51, 8, 4, 11})
+//	plot.RenamePointGroup("Sample1", "Latency")
+func (plot *plot) RenamePointGroup(name, newName string) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	pointGroup, exists := plot.pointGroup[name]
+	if !exists {
+		return &GnuplotError{err: fmt.Sprintf("A curve with name %s does not exist.", name)}
+	}
+	if name == newName {
+		return nil
+	}
+	err := plot.checkNewName(newName)
+	if err != nil {
+		return err
+	}
 
+	order := slices.Clone(plot.order)
+	delete(plot.pointGroup, name)
+	pointGroup.name = newName
+	plot.pointGroup[newName] = pointGroup
+	plot.order[slices.Index(plot.order, name)] = newName
+	err = plot.replot()
+	if err != nil {
+		// keep the name gnuplot accepted
+		delete(plot.pointGroup, newName)
+		pointGroup.name = name
+		plot.pointGroup[name] = pointGroup
+		plot.order = order
+		return err
 	}
-	return err
+	return nil
+}
+
+// ListPointGroups returns the names of the point groups in the order they
+// are drawn.
+func (plot *plot) ListPointGroups() []string {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return slices.Clone(plot.order)
+}
+
+// MovePointGroup moves a point group to the given position in the plot.
+// The position defines the drawing order, and so the color and the place in
+// the legend assigned by gnuplot.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.AddPointGroup("Sample1", "lines", []int32{51, 8, 4, 11})
+//	plot.AddPointGroup("Sample2", "lines", []int32{1, 2, 4, 11})
+//	plot.MovePointGroup("Sample2", 0)
+func (plot *plot) MovePointGroup(name string, index int) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	from := slices.Index(plot.order, name)
+	if from < 0 {
+		return &GnuplotError{err: fmt.Sprintf("A curve with name %s does not exist.", name)}
+	}
+	if index < 0 || index >= len(plot.order) {
+		return &GnuplotError{err: fmt.Sprintf("invalid position %d, the plot has %d curves", index, len(plot.order))}
+	}
+	order := slices.Clone(plot.order)
+	plot.order = slices.Delete(plot.order, from, from+1)
+	plot.order = slices.Insert(plot.order, index, name)
+	err := plot.replot()
+	if err != nil {
+		plot.order = order
+		return err
+	}
+	return nil
 }
 
 // RemovePointGroup helps to remove a particular point group from the plot.
@@ -127,12 +444,25 @@ func (plot *plot) AddPointGroup(name string, style Style, data any) (err error)
 //	plot.AddPointGroup("Sample1", "points", []int32{51, 8, 4, 11})
 //	plot.AddPointGroup("Sample2", "points", []int32{1, 2, 4, 11})
 //	plot.RemovePointGroup("Sample1")
-func (plot *plot) RemovePointGroup(name string) {
+func (plot *plot) RemovePointGroup(name string) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	pointGroup, exists := plot.pointGroup[name]
+	if !exists {
+		return &GnuplotError{err: fmt.Sprintf("A curve with name %s does not exist.", name)}
+	}
+	// gnuplot stops drawing the point group before its data is removed
+	order := slices.Clone(plot.order)
 	delete(plot.pointGroup, name)
-	plot.cleanplot()
-	for _, pointGroup := range plot.pointGroup {
-		plot.plotX(pointGroup)
+	plot.order = slices.DeleteFunc(plot.order, func(n string) bool { return n == name })
+	err := plot.replot()
+	if err != nil {
+		plot.pointGroup[name] = pointGroup
+		plot.order = order
+		return err
 	}
+	return plot.removeData(pointGroup)
 }
 
 // ResetPointGroupStyle helps to reset the style of a particular point group in a plot.
@@ -146,14 +476,38 @@ func (plot *plot) RemovePointGroup(name string) {
 //	debug := false
 //	plot, _ := glot.NewPlot(dimensions, persist, debug)
 //	plot.AddPointGroup("Sample1", "points", []int32{51, 8, 4, 11})
-//	plot.ResetPointGroupStyle("Sample1", "points")
-func (plot *plot) ResetPointGroupStyle(name string, style string) (err error) {
+//	plot.ResetPointGroupStyle("Sample1", "lines", glot.WithLineWidth(3))
+//
+// The options are applied on top of the current options of the point group.
+func (plot *plot) ResetPointGroupStyle(name string, style Style, opts ...PointGroupOption) (err error) {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
 	pointGroup, exists := plot.pointGroup[name]
 	if !exists {
-		return &gnuplotError{fmt.Sprintf("A curve with name %s does not exist.", name)}
+		return &GnuplotError{err: fmt.Sprintf("A curve with name %s does not exist.", name)}
+	}
+	// heatmaps, bar charts, histograms and expressions pick their own columns
+	if pointGroup.heatmap == nil && pointGroup.bars == nil && pointGroup.using == "" && pointGroup.expression == "" {
+		err = plot.checkColumns(pointGroup.columns, style)
+		if err != nil {
+			return err
+		}
 	}
-	plot.RemovePointGroup(name)
-	pointGroup.style = style
-	plot.plotX(pointGroup)
-	return err
+	options := pointGroup.options
+	options.apply(opts)
+	err = options.check(plot.dimensions)
+	if err != nil {
+		return err
+	}
+	oldStyle, oldOptions := pointGroup.style, pointGroup.options
+	pointGroup.style = string(style)
+	pointGroup.options = options
+	err = plot.replot()
+	if err != nil {
+		// keep drawing the point group the way gnuplot accepted
+		pointGroup.style, pointGroup.options = oldStyle, oldOptions
+		return err
+	}
+	return nil
 }
@@ -0,0 +1,87 @@
+package glot
+
+import (
+	"fmt"
+	"iter"
+)
+
+// XY is a point of a 2 dimensional plot, such as a struct of measures, see
+// AddPoints.
+type XY interface {
+	XY() (x, y float64)
+}
+
+// XYZ is a point of a 3 dimensional plot, see AddPoints3D.
+type XYZ interface {
+	XYZ() (x, y, z float64)
+}
+
+// AddSeries adds a point group of the given x and y coordinates to the plot.
+// Unlike AddPointGroup, the type of the coordinates is checked at compile
+// time.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	glot.AddSeries(plot, "requests", glot.StyleLines, []uint64{1, 2, 3}, []uint64{120, 80, 95})
+func AddSeries[T Number](p Plot, name string, style Style, xs, ys []T, opts ...PointGroupOption) error {
+	if len(xs) != len(ys) {
+		return &GnuplotError{err: fmt.Sprintf("the series %s has %d x and %d y coordinates", name, len(xs), len(ys))}
+	}
+	return p.AddPointGroup(name, style, [][]float64{toFloat64(xs), toFloat64(ys)}, opts...)
+}
+
+// AddSeries3D adds a point group of the given x, y and z coordinates to a 3
+// dimensional plot.
+func AddSeries3D[T Number](p Plot, name string, style Style, xs, ys, zs []T, opts ...PointGroupOption) error {
+	if len(xs) != len(ys) || len(xs) != len(zs) {
+		return &GnuplotError{err: fmt.Sprintf("the series %s has %d x, %d y and %d z coordinates", name, len(xs), len(ys), len(zs))}
+	}
+	return p.AddPointGroup(name, style, [][]float64{toFloat64(xs), toFloat64(ys), toFloat64(zs)}, opts...)
+}
+
+// AddSeq adds a point group of the x and y coordinates yielded by seq, such as
+// the keys and the values of a sorted map.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	glot.AddSeq(plot, "latency", glot.StyleLines, slices.All(latencies))
+func AddSeq[X, Y Number](p Plot, name string, style Style, seq iter.Seq2[X, Y], opts ...PointGroupOption) error {
+	var xs, ys []float64
+	for x, y := range seq {
+		xs = append(xs, float64(x))
+		ys = append(ys, float64(y))
+	}
+	return p.AddPointGroup(name, style, [][]float64{xs, ys}, opts...)
+}
+
+// AddPoints adds a point group of points implementing XY to the plot.
+//
+// Usage
+//
+//	type sample struct {
+//		At       time.Duration
+//		Requests int
+//	}
+//
+//	func (s sample) XY() (float64, float64) { return s.At.Seconds(), float64(s.Requests) }
+//
+//	glot.AddPoints(plot, "requests", glot.StyleLines, samples)
+func AddPoints[P XY](p Plot, name string, style Style, points []P, opts ...PointGroupOption) error {
+	columns := [][]float64{make([]float64, len(points)), make([]float64, len(points))}
+	for i, point := range points {
+		columns[0][i], columns[1][i] = point.XY()
+	}
+	return p.AddPointGroup(name, style, columns, opts...)
+}
+
+// AddPoints3D adds a point group of points implementing XYZ to a 3
+// dimensional plot.
+func AddPoints3D[P XYZ](p Plot, name string, style Style, points []P, opts ...PointGroupOption) error {
+	columns := [][]float64{make([]float64, len(points)), make([]float64, len(points)), make([]float64, len(points))}
+	for i, point := range points {
+		columns[0][i], columns[1][i], columns[2][i] = point.XYZ()
+	}
+	return p.AddPointGroup(name, style, columns, opts...)
+}
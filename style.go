@@ -0,0 +1,224 @@
+package glot
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Color is the color of a point group: either a gnuplot color name such as
+// "red" or "dark-green", or a hex value "#rrggbb".
+type Color string
+
+// RGB returns the color with the given red, green and blue components.
+func RGB(r, g, b uint8) Color {
+	return Color(fmt.Sprintf("#%02x%02x%02x", r, g, b))
+}
+
+// withTransparency adds an alpha channel to a hex color. gnuplot has no way
+// to make a named color transparent, so those are returned unchanged.
+func (color Color) withTransparency(transparency float64) string {
+	if transparency <= 0 || len(color) != 7 || color[0] != '#' {
+		return string(color)
+	}
+	alpha := uint8(math.Round(min(transparency, 1) * 255))
+	return fmt.Sprintf("#%02x%s", alpha, color[1:])
+}
+
+// Fill is the fill style of boxes, histograms and filled curves.
+type Fill struct {
+	Density float64 // density of a solid fill from 0 to 1, 0 means fully solid
+	Pattern int     // pattern number, used instead of a solid fill when positive
+	Border  bool    // draw the border of the filled area
+}
+
+// Smoothing selects how gnuplot interpolates or approximates the points of
+// a point group before drawing them.
+type Smoothing string
+
+const (
+	SmoothCSplines   Smoothing = "csplines"   // natural cubic splines through the points
+	SmoothACSplines  Smoothing = "acsplines"  // weighted approximation by cubic splines
+	SmoothBezier     Smoothing = "bezier"     // Bezier curve of the points
+	SmoothSBezier    Smoothing = "sbezier"    // Bezier curve of the unique points
+	SmoothUnique     Smoothing = "unique"     // average of the points sharing an x
+	SmoothFrequency  Smoothing = "frequency"  // sum of the y of the points sharing an x
+	SmoothCumulative Smoothing = "cumulative" // running sum of the y of the points sorted by x
+	SmoothCNormal    Smoothing = "cnormal"    // running sum normalized to end with 1
+	SmoothKDensity   Smoothing = "kdensity"   // kernel density estimate of the x weighted by the y
+)
+
+// distribution reports whether the smoothing reads the x of the points as
+// values weighted by their y. Point groups of one column are the values
+// themselves, weighted by 1.
+func (smoothing Smoothing) distribution() bool {
+	switch smoothing {
+	case SmoothFrequency, SmoothCumulative, SmoothCNormal, SmoothKDensity:
+		return true
+	}
+	return false
+}
+
+// PointGroupOptions describes how a point group is drawn.
+// The zero value of every field keeps the gnuplot default.
+type PointGroupOptions struct {
+	Color        Color     // line and fill color
+	LineWidth    float64   // line width, 1 is the default width
+	DashType     int       // gnuplot dash type, 1 is a solid line
+	PointType    int       // gnuplot point type
+	PointSize    float64   // point size, 1 is the default size
+	Fill         *Fill     // fill style of boxes and filled curves
+	Transparency float64   // transparency from 0, opaque, to 1, invisible, of a hex Color and of a solid Fill
+	Smooth       Smoothing // interpolation of the points
+	Axes         Axes      // axes the point group is drawn against
+}
+
+// PointGroupOption changes the options of a point group.
+type PointGroupOption func(*PointGroupOptions)
+
+// WithOptions replaces all the options of a point group.
+func WithOptions(options PointGroupOptions) PointGroupOption {
+	return func(opts *PointGroupOptions) {
+		*opts = options
+	}
+}
+
+// WithColor sets the color of a point group.
+func WithColor(color Color) PointGroupOption {
+	return func(opts *PointGroupOptions) {
+		opts.Color = color
+	}
+}
+
+// WithRGB sets the color of a point group from its red, green and blue components.
+func WithRGB(r, g, b uint8) PointGroupOption {
+	return WithColor(RGB(r, g, b))
+}
+
+// WithLineWidth sets the line width of a point group.
+func WithLineWidth(width float64) PointGroupOption {
+	return func(opts *PointGroupOptions) {
+		opts.LineWidth = width
+	}
+}
+
+// WithDashType sets the gnuplot dash type of a point group.
+func WithDashType(dashType int) PointGroupOption {
+	return func(opts *PointGroupOptions) {
+		opts.DashType = dashType
+	}
+}
+
+// WithPointType sets the gnuplot point type of a point group.
+func WithPointType(pointType int) PointGroupOption {
+	return func(opts *PointGroupOptions) {
+		opts.PointType = pointType
+	}
+}
+
+// WithPointSize sets the point size of a point group.
+func WithPointSize(size float64) PointGroupOption {
+	return func(opts *PointGroupOptions) {
+		opts.PointSize = size
+	}
+}
+
+// WithFill sets the fill style of a point group.
+func WithFill(fill Fill) PointGroupOption {
+	return func(opts *PointGroupOptions) {
+		opts.Fill = &fill
+	}
+}
+
+// WithTransparency sets the transparency of a point group from 0, opaque, to
+// 1, invisible.
+func WithTransparency(transparency float64) PointGroupOption {
+	return func(opts *PointGroupOptions) {
+		opts.Transparency = transparency
+	}
+}
+
+// WithSmoothing sets the interpolation of the points of a point group.
+func WithSmoothing(smoothing Smoothing) PointGroupOption {
+	return func(opts *PointGroupOptions) {
+		opts.Smooth = smoothing
+	}
+}
+
+// WithAxes binds a point group to a pair of axes.
+func WithAxes(axes Axes) PointGroupOption {
+	return func(opts *PointGroupOptions) {
+		opts.Axes = axes
+	}
+}
+
+// apply applies the options in order.
+func (opts *PointGroupOptions) apply(options []PointGroupOption) {
+	for _, option := range options {
+		option(opts)
+	}
+}
+
+// check reports invalid options.
+func (opts *PointGroupOptions) check(dimensions int) error {
+	if opts.Axes != "" && !opts.Axes.valid() {
+		return &GnuplotError{err: fmt.Sprintf("unknown axes '%s'", opts.Axes)}
+	}
+	if opts.Axes != "" && dimensions == 3 {
+		return &GnuplotError{err: "3 dimensional plots have no secondary axes"}
+	}
+	if opts.Smooth != "" && dimensions == 3 {
+		return &GnuplotError{err: "the points of 3 dimensional plots can't be smoothed"}
+	}
+	if opts.Transparency < 0 || opts.Transparency > 1 {
+		return &GnuplotError{err: fmt.Sprintf("invalid transparency %v", opts.Transparency)}
+	}
+	return nil
+}
+
+// styleClause returns the style modifiers following "with <style>" in the
+// plot command.
+func (opts *PointGroupOptions) styleClause() string {
+	var clause []string
+	if opts.Color != "" {
+		clause = append(clause, "lc rgb "+quote(opts.Color.withTransparency(opts.Transparency)))
+	}
+	if opts.LineWidth > 0 {
+		clause = append(clause, "lw "+formatFloat(opts.LineWidth))
+	}
+	if opts.DashType > 0 {
+		clause = append(clause, "dt "+strconv.Itoa(opts.DashType))
+	}
+	if opts.PointType > 0 {
+		clause = append(clause, "pt "+strconv.Itoa(opts.PointType))
+	}
+	if opts.PointSize > 0 {
+		clause = append(clause, "ps "+formatFloat(opts.PointSize))
+	}
+	if opts.Fill != nil {
+		clause = append(clause, opts.fillClause())
+	}
+	return strings.Join(clause, " ")
+}
+
+func (opts *PointGroupOptions) fillClause() string {
+	var fill string
+	if opts.Fill.Pattern > 0 {
+		fill = "fs pattern " + strconv.Itoa(opts.Fill.Pattern)
+	} else {
+		density := opts.Fill.Density
+		if density <= 0 {
+			density = 1
+		}
+		if opts.Transparency > 0 {
+			fill = "fs transparent solid " + formatFloat(density*(1-opts.Transparency))
+		} else {
+			fill = "fs solid " + formatFloat(density)
+		}
+	}
+	if opts.Fill.Border {
+		return fill + " border"
+	}
+	return fill + " noborder"
+}
@@ -0,0 +1,242 @@
+package glot
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Grid is a surface sampled on a regular mesh: Z[i][j] is the height of the
+// surface at x = X[j], y = Y[i].
+type Grid struct {
+	X, Y []float64
+	Z    [][]float64
+}
+
+// SampleGrid samples f on a mesh of nx by ny points evenly spread over the
+// given ranges.
+//
+// Usage
+//
+//	grid := glot.SampleGrid(func(x, y float64) float64 {
+//		return math.Sin(x) * math.Cos(y)
+//	}, -math.Pi, math.Pi, 40, -math.Pi, math.Pi, 40)
+func SampleGrid(f func(x, y float64) float64, xmin, xmax float64, nx int, ymin, ymax float64, ny int) Grid {
+	grid := Grid{X: linspace(xmin, xmax, nx), Y: linspace(ymin, ymax, ny)}
+	grid.Z = make([][]float64, len(grid.Y))
+	for i, y := range grid.Y {
+		grid.Z[i] = make([]float64, len(grid.X))
+		for j, x := range grid.X {
+			grid.Z[i][j] = f(x, y)
+		}
+	}
+	return grid
+}
+
+// linspace returns n values evenly spread from start to end.
+func linspace(start, end float64, n int) []float64 {
+	if n <= 0 {
+		return nil
+	}
+	if n == 1 {
+		return []float64{start}
+	}
+	values := make([]float64, n)
+	step := (end - start) / float64(n-1)
+	for i := range values {
+		values[i] = start + float64(i)*step
+	}
+	values[n-1] = end
+	return values
+}
+
+// columns returns the x, y and z columns of the grid, one scan per row of
+// Z, together with the length of the scans.
+func (grid Grid) columns() ([][]float64, int, error) {
+	if len(grid.X) == 0 || len(grid.Y) == 0 {
+		return nil, 0, &GnuplotError{err: "the grid of a surface must not be empty"}
+	}
+	if len(grid.Z) != len(grid.Y) {
+		return nil, 0, &GnuplotError{err: fmt.Sprintf("the grid has %d rows of z instead of %d", len(grid.Z), len(grid.Y))}
+	}
+	npoints := len(grid.X) * len(grid.Y)
+	xs := make([]float64, 0, npoints)
+	ys := make([]float64, 0, npoints)
+	zs := make([]float64, 0, npoints)
+	for i, row := range grid.Z {
+		if len(row) != len(grid.X) {
+			return nil, 0, &GnuplotError{err: fmt.Sprintf("row %d of z has %d values instead of %d", i, len(row), len(grid.X))}
+		}
+		xs = append(xs, grid.X...)
+		for range row {
+			ys = append(ys, grid.Y[i])
+		}
+		zs = append(zs, row...)
+	}
+	return [][]float64{xs, ys, zs}, len(grid.X), nil
+}
+
+// AddSurface adds a surface sampled on a regular grid to a 3 dimensional
+// plot. Drawn with lines, the surface is a mesh; drawn with StylePm3d, it
+// is colored according to the palette.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(3, false)
+//	grid := glot.SampleGrid(func(x, y float64) float64 { return x * y }, -1, 1, 20, -1, 1, 20)
+//	plot.AddSurface("saddle", glot.StylePm3d, grid)
+//	plot.SetView(60, 30)
+func (plot *plot) AddSurface(name string, style Style, grid Grid, opts ...PointGroupOption) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	err := plot.checkNewName(name)
+	if err != nil {
+		return err
+	}
+	if plot.dimensions != 3 {
+		return &GnuplotError{err: "surfaces can only be drawn on 3 dimensional plots"}
+	}
+	columns, scanLength, err := grid.columns()
+	if err != nil {
+		return err
+	}
+	curve := &pointGroup{name: name, dimensions: 3, data: grid, set: true, style: string(style)}
+	curve.options.apply(opts)
+	err = curve.options.check(plot.dimensions)
+	if err != nil {
+		return err
+	}
+	curve.columns = columns
+	curve.scanLength = scanLength
+	return plot.addPointGroup(curve)
+}
+
+// Pm3dPosition is where pm3d draws the colored surfaces.
+type Pm3dPosition string
+
+const (
+	Pm3dAtSurface Pm3dPosition = "s" // on the surface itself
+	Pm3dAtBase    Pm3dPosition = "b" // on the base of the plot, as a color map
+	Pm3dAtTop     Pm3dPosition = "t" // on the top of the plot
+)
+
+// SetPm3d colors all the surfaces of the plot according to the palette at the
+// given positions, on the surfaces themselves by default.
+//
+// Usage
+//
+//	plot.SetPm3d(glot.Pm3dAtSurface, glot.Pm3dAtBase)
+func (plot *plot) SetPm3d(positions ...Pm3dPosition) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	command := "set pm3d implicit"
+	if len(positions) > 0 {
+		var at strings.Builder
+		for _, position := range positions {
+			switch position {
+			case Pm3dAtSurface, Pm3dAtBase, Pm3dAtTop:
+				at.WriteString(string(position))
+			default:
+				return &GnuplotError{err: fmt.Sprintf("unknown pm3d position '%s'", position)}
+			}
+		}
+		command += " at " + at.String()
+	}
+	return plot.set("pm3d", command)
+}
+
+// ContourOptions describes the contour lines of the surfaces.
+type ContourOptions struct {
+	Base    bool      // draw the contour lines on the base of the plot
+	Surface bool      // draw the contour lines on the surfaces
+	Levels  []float64 // heights of the contour lines, chosen by gnuplot if empty
+	Count   int       // approximate number of levels chosen by gnuplot when Levels is empty, 0 for the default
+}
+
+// SetContour draws contour lines of the surfaces.
+//
+// Usage
+//
+//	plot.SetContour(glot.ContourOptions{Base: true, Levels: []float64{-0.5, 0, 0.5}})
+func (plot *plot) SetContour(options ContourOptions) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	var where string
+	switch {
+	case options.Base && options.Surface:
+		where = "both"
+	case options.Base:
+		where = "base"
+	case options.Surface:
+		where = "surface"
+	default:
+		return plot.set("contour", "unset contour")
+	}
+	if options.Count < 0 {
+		return &GnuplotError{err: fmt.Sprintf("invalid number of contour levels %d", options.Count)}
+	}
+
+	levels := "set cntrparam levels auto"
+	if len(options.Levels) > 0 {
+		values := make([]string, len(options.Levels))
+		for i, level := range options.Levels {
+			values[i] = formatFloat(level)
+		}
+		levels = "set cntrparam levels discrete " + strings.Join(values, ",")
+	} else if options.Count > 0 {
+		levels += fmt.Sprintf(" %d", options.Count)
+	}
+	err := plot.set("cntrparam", levels)
+	if err != nil {
+		return err
+	}
+	return plot.set("contour", "set contour "+where)
+}
+
+// SetView sets the angles the 3 dimensional plot is seen from: the rotation
+// around the x axis and then around the z axis, in degrees.
+//
+// Usage
+//
+//	plot.SetView(60, 30)
+func (plot *plot) SetView(rotX, rotZ float64) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.set("view", fmt.Sprintf("set view %s,%s", formatFloat(rotX), formatFloat(rotZ)))
+}
+
+// SetViewMap shows the 3 dimensional plot from above, as a map.
+func (plot *plot) SetViewMap() error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.set("view", "set view map")
+}
+
+// SetHidden3d hides the parts of the surfaces drawn with lines which are
+// behind other surfaces.
+func (plot *plot) SetHidden3d() error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.set("hidden3d", "set hidden3d")
+}
+
+// SetDgrid3d makes gnuplot interpolate the scattered points of the 3
+// dimensional point groups on a grid of the given number of rows and
+// columns, so they are drawn as surfaces.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(3, false)
+//	plot.SetDgrid3d(30, 30)
+//	plot.AddPointGroup("measures", glot.StyleLines, [][]float64{xs, ys, zs})
+func (plot *plot) SetDgrid3d(rows, cols int) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	if rows < 2 || cols < 2 {
+		return &GnuplotError{err: fmt.Sprintf("invalid dgrid3d size %dx%d", rows, cols)}
+	}
+	return plot.set("dgrid3d", fmt.Sprintf("set dgrid3d %d,%d", rows, cols))
+}
@@ -0,0 +1,509 @@
+package glot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Terminal describes a gnuplot terminal together with its options.
+// Every supported format has its own options type, e.g. PngOptions or
+// PdfCairoOptions, which generates the matching "set terminal" command.
+type Terminal interface {
+	// Format returns the output format produced by the terminal.
+	Format() Format
+
+	// command returns the command selecting the terminal for a plot
+	// of the given size.
+	command(width, height int) string
+}
+
+// Font describes the font used by a terminal.
+// An empty name or a zero size keeps the terminal default.
+type Font struct {
+	Name string
+	Size float64
+}
+
+// Unit is the unit of the plot size for terminals producing vector output.
+type Unit string
+
+const (
+	// UnitPixels means the size is given in pixels. Terminals measuring
+	// their size in inches get it converted at 72 pixels per inch.
+	UnitPixels      Unit = ""
+	UnitInches      Unit = "in"
+	UnitCentimeters Unit = "cm"
+)
+
+// gPixelsPerInch is the resolution used to convert pixel sizes for
+// terminals measuring their size in inches.
+const gPixelsPerInch = 72
+
+// terminalLine builds a "set terminal" command option by option.
+type terminalLine []string
+
+func newTerminalLine(name string) *terminalLine {
+	return &terminalLine{"set terminal", name}
+}
+
+func (line *terminalLine) add(options ...string) {
+	*line = append(*line, options...)
+}
+
+func (line *terminalLine) flag(set bool, option string) {
+	if set {
+		line.add(option)
+	}
+}
+
+func (line *terminalLine) font(font Font) {
+	if font.Name == "" && font.Size == 0 {
+		return
+	}
+	desc := font.Name
+	if font.Size > 0 {
+		desc += "," + strconv.FormatFloat(font.Size, 'g', -1, 64)
+	}
+	line.add("font", quote(desc))
+}
+
+func (line *terminalLine) background(color string) {
+	if color != "" {
+		line.add("background rgb", quote(color))
+	}
+}
+
+// pixels sets a size measured in pixels or characters.
+func (line *terminalLine) pixels(width, height int) {
+	line.add(fmt.Sprintf("size %d,%d", width, height))
+}
+
+// inches sets the size of a terminal measuring its size in inches.
+func (line *terminalLine) inches(width, height int, unit Unit) {
+	if unit == UnitPixels {
+		line.add(fmt.Sprintf("size %gin,%gin",
+			float64(width)/gPixelsPerInch, float64(height)/gPixelsPerInch))
+		return
+	}
+	line.add(fmt.Sprintf("size %d%s,%d%s", width, unit, height, unit))
+}
+
+func (line *terminalLine) String() string {
+	return strings.Join(*line, " ")
+}
+
+// PngOptions configures the png terminal based on libgd.
+type PngOptions struct {
+	Font        Font
+	Enhanced    bool   // enable enhanced text mode
+	Transparent bool   // make the background transparent
+	Background  string // background color, a name or "#rrggbb"
+	Crop        bool   // trim blank space around the plot
+}
+
+func (opts PngOptions) Format() Format {
+	return FormatPng
+}
+
+func (opts PngOptions) command(width, height int) string {
+	line := newTerminalLine("png")
+	line.flag(opts.Enhanced, "enhanced")
+	line.flag(opts.Transparent, "transparent")
+	line.flag(opts.Crop, "crop")
+	line.font(opts.Font)
+	line.background(opts.Background)
+	line.pixels(width, height)
+	return line.String()
+}
+
+// PngCairoOptions configures the pngcairo terminal.
+type PngCairoOptions struct {
+	Font        Font
+	Enhanced    bool   // enable enhanced text mode
+	Transparent bool   // make the background transparent
+	Background  string // background color, a name or "#rrggbb"
+	Crop        bool   // trim blank space around the plot
+	Monochrome  bool   // draw in black and white
+}
+
+func (opts PngCairoOptions) Format() Format {
+	return FormatPngCairo
+}
+
+func (opts PngCairoOptions) command(width, height int) string {
+	line := newTerminalLine("pngcairo")
+	line.flag(opts.Enhanced, "enhanced")
+	line.flag(opts.Monochrome, "mono")
+	line.flag(opts.Transparent, "transparent")
+	line.flag(opts.Crop, "crop")
+	line.font(opts.Font)
+	line.background(opts.Background)
+	line.pixels(width, height)
+	return line.String()
+}
+
+// JpegOptions configures the jpeg terminal.
+type JpegOptions struct {
+	Font       Font
+	Enhanced   bool   // enable enhanced text mode
+	Background string // background color, a name or "#rrggbb"
+	Crop       bool   // trim blank space around the plot
+}
+
+func (opts JpegOptions) Format() Format {
+	return FormatJpeg
+}
+
+func (opts JpegOptions) command(width, height int) string {
+	line := newTerminalLine("jpeg")
+	line.flag(opts.Enhanced, "enhanced")
+	line.flag(opts.Crop, "crop")
+	line.font(opts.Font)
+	line.background(opts.Background)
+	line.pixels(width, height)
+	return line.String()
+}
+
+// GifOptions configures the gif terminal.
+type GifOptions struct {
+	Font        Font
+	Enhanced    bool   // enable enhanced text mode
+	Transparent bool   // make the background transparent
+	Background  string // background color, a name or "#rrggbb"
+	Crop        bool   // trim blank space around the plot
+}
+
+func (opts GifOptions) Format() Format {
+	return FormatGif
+}
+
+func (opts GifOptions) command(width, height int) string {
+	line := newTerminalLine("gif")
+	line.flag(opts.Enhanced, "enhanced")
+	line.flag(opts.Transparent, "transparent")
+	line.flag(opts.Crop, "crop")
+	line.font(opts.Font)
+	line.background(opts.Background)
+	line.pixels(width, height)
+	return line.String()
+}
+
+// SixelOptions configures the sixelgd terminal for sixel capable consoles.
+type SixelOptions struct {
+	Font        Font
+	Enhanced    bool   // enable enhanced text mode
+	Transparent bool   // make the background transparent
+	Background  string // background color, a name or "#rrggbb"
+	Truecolor   bool   // use 24 bit colors instead of a palette
+}
+
+func (opts SixelOptions) Format() Format {
+	return FormatSixel
+}
+
+func (opts SixelOptions) command(width, height int) string {
+	line := newTerminalLine("sixelgd")
+	line.flag(opts.Enhanced, "enhanced")
+	line.flag(opts.Truecolor, "truecolor")
+	line.flag(opts.Transparent, "transparent")
+	line.font(opts.Font)
+	line.background(opts.Background)
+	line.pixels(width, height)
+	return line.String()
+}
+
+// SvgOptions configures the svg terminal. The size is given in pixels.
+type SvgOptions struct {
+	Font       Font
+	Enhanced   bool   // enable enhanced text mode
+	Background string // background color, a name or "#rrggbb"
+	Dynamic    bool   // let the viewer resize the image
+}
+
+func (opts SvgOptions) Format() Format {
+	return FormatSvg
+}
+
+func (opts SvgOptions) command(width, height int) string {
+	line := newTerminalLine("svg")
+	line.pixels(width, height)
+	line.flag(opts.Dynamic, "dynamic")
+	line.flag(opts.Enhanced, "enhanced")
+	line.font(opts.Font)
+	line.background(opts.Background)
+	return line.String()
+}
+
+// CanvasOptions configures the canvas terminal producing HTML5 javascript.
+// The size is given in pixels.
+type CanvasOptions struct {
+	Font       Font
+	Enhanced   bool   // enable enhanced text mode
+	Background string // background color, a name or "#rrggbb"
+	Standalone bool   // produce a complete HTML page
+	Name       string // name of the javascript drawing function
+}
+
+func (opts CanvasOptions) Format() Format {
+	return FormatCanvas
+}
+
+func (opts CanvasOptions) command(width, height int) string {
+	line := newTerminalLine("canvas")
+	line.pixels(width, height)
+	line.flag(opts.Enhanced, "enhanced")
+	line.font(opts.Font)
+	line.background(opts.Background)
+	if opts.Name != "" {
+		line.add("name", quote(opts.Name))
+	} else {
+		line.flag(opts.Standalone, "standalone")
+	}
+	return line.String()
+}
+
+// PdfOptions configures the pdf terminal.
+// The size is converted to inches unless Units says otherwise.
+type PdfOptions struct {
+	Font       Font
+	Enhanced   bool   // enable enhanced text mode
+	Background string // background color, a name or "#rrggbb"
+	Monochrome bool   // draw in black and white
+	Units      Unit   // unit of the plot size
+}
+
+func (opts PdfOptions) Format() Format {
+	return FormatPdf
+}
+
+func (opts PdfOptions) command(width, height int) string {
+	line := newTerminalLine("pdf")
+	line.flag(opts.Enhanced, "enhanced")
+	line.flag(opts.Monochrome, "mono")
+	line.font(opts.Font)
+	line.background(opts.Background)
+	line.inches(width, height, opts.Units)
+	return line.String()
+}
+
+// PdfCairoOptions configures the pdfcairo terminal.
+// The size is converted to inches unless Units says otherwise.
+type PdfCairoOptions struct {
+	Font       Font
+	Enhanced   bool   // enable enhanced text mode
+	Background string // background color, a name or "#rrggbb"
+	Monochrome bool   // draw in black and white
+	Units      Unit   // unit of the plot size
+}
+
+func (opts PdfCairoOptions) Format() Format {
+	return FormatPdfCairo
+}
+
+func (opts PdfCairoOptions) command(width, height int) string {
+	line := newTerminalLine("pdfcairo")
+	line.flag(opts.Enhanced, "enhanced")
+	line.flag(opts.Monochrome, "mono")
+	line.font(opts.Font)
+	line.background(opts.Background)
+	line.inches(width, height, opts.Units)
+	return line.String()
+}
+
+// PostscriptOptions configures the postscript terminal.
+// The size is converted to inches unless Units says otherwise.
+type PostscriptOptions struct {
+	Font       Font
+	Enhanced   bool   // enable enhanced text mode
+	Background string // background color, a name or "#rrggbb"
+	Monochrome bool   // draw in black and white
+	Landscape  bool   // rotate the page
+	Units      Unit   // unit of the plot size
+}
+
+func (opts PostscriptOptions) Format() Format {
+	return FormatPostscript
+}
+
+func (opts PostscriptOptions) command(width, height int) string {
+	line := newTerminalLine("postscript")
+	if opts.Landscape {
+		line.add("landscape")
+	} else {
+		line.add("portrait")
+	}
+	line.flag(opts.Enhanced, "enhanced")
+	postscriptColor(line, opts.Monochrome)
+	line.font(opts.Font)
+	line.background(opts.Background)
+	line.inches(width, height, opts.Units)
+	return line.String()
+}
+
+// EpsOptions configures the postscript terminal in encapsulated mode.
+// The size is converted to inches unless Units says otherwise.
+type EpsOptions struct {
+	Font       Font
+	Enhanced   bool   // enable enhanced text mode
+	Background string // background color, a name or "#rrggbb"
+	Monochrome bool   // draw in black and white
+	Units      Unit   // unit of the plot size
+}
+
+func (opts EpsOptions) Format() Format {
+	return FormatEps
+}
+
+func (opts EpsOptions) command(width, height int) string {
+	line := newTerminalLine("postscript eps")
+	line.flag(opts.Enhanced, "enhanced")
+	postscriptColor(line, opts.Monochrome)
+	line.font(opts.Font)
+	line.background(opts.Background)
+	line.inches(width, height, opts.Units)
+	return line.String()
+}
+
+// postscript draws in black and white unless asked otherwise.
+func postscriptColor(line *terminalLine, monochrome bool) {
+	if monochrome {
+		line.add("monochrome")
+	} else {
+		line.add("color")
+	}
+}
+
+// DumbOptions configures the dumb terminal drawing ASCII art.
+// The size is given in characters.
+type DumbOptions struct {
+	Enhanced bool // enable enhanced text mode
+	Feed     bool // print a form feed after every plot
+	ANSI     bool // use ANSI escape sequences for colors
+}
+
+func (opts DumbOptions) Format() Format {
+	return FormatDumb
+}
+
+func (opts DumbOptions) command(width, height int) string {
+	line := newTerminalLine("dumb")
+	if opts.Feed {
+		line.add("feed")
+	} else {
+		line.add("nofeed")
+	}
+	line.flag(opts.Enhanced, "enhanced")
+	line.flag(opts.ANSI, "ansi")
+	line.pixels(width, height)
+	return line.String()
+}
+
+// LatexOptions configures the latex terminal producing a picture environment.
+// Font.Name must be "roman" or "courier" and defaults to roman.
+// The size is converted to inches unless Units says otherwise.
+type LatexOptions struct {
+	Font   Font
+	Rotate bool // allow rotated text
+	Units  Unit // unit of the plot size
+}
+
+func (opts LatexOptions) Format() Format {
+	return FormatLatex
+}
+
+func (opts LatexOptions) command(width, height int) string {
+	line := newTerminalLine("latex")
+	if opts.Font.Name != "" || opts.Font.Size > 0 {
+		name := opts.Font.Name
+		if name == "" {
+			name = "roman"
+		}
+		line.add(name)
+		if opts.Font.Size > 0 {
+			line.add(strconv.FormatFloat(opts.Font.Size, 'g', -1, 64))
+		}
+	}
+	line.inches(width, height, opts.Units)
+	if opts.Rotate {
+		line.add("rotate")
+	} else {
+		line.add("norotate")
+	}
+	return line.String()
+}
+
+// TikzOptions configures the tikz terminal of the gnuplot-lua-tikz package.
+// The size is converted to inches unless Units says otherwise.
+type TikzOptions struct {
+	Font       Font
+	Monochrome bool // draw in black and white
+	Standalone bool // produce a complete LaTeX document
+	Units      Unit // unit of the plot size
+}
+
+func (opts TikzOptions) Format() Format {
+	return FormatTikz
+}
+
+func (opts TikzOptions) command(width, height int) string {
+	line := newTerminalLine("tikz")
+	if opts.Monochrome {
+		line.add("monochrome")
+	} else {
+		line.add("color")
+	}
+	line.flag(opts.Standalone, "standalone")
+	line.font(opts.Font)
+	line.inches(width, height, opts.Units)
+	return line.String()
+}
+
+// rawTerminal selects a terminal which has no options type by its name.
+type rawTerminal Format
+
+func (term rawTerminal) Format() Format {
+	return Format(term)
+}
+
+func (term rawTerminal) command(width, height int) string {
+	return "set terminal " + string(term) +
+		" size " + strconv.Itoa(width) + ", " + strconv.Itoa(height)
+}
+
+// defaultTerminal returns the terminal producing the format with the
+// default options.
+func defaultTerminal(format Format) Terminal {
+	switch format {
+	case FormatPng:
+		return PngOptions{}
+	case FormatPngCairo:
+		return PngCairoOptions{}
+	case FormatJpeg:
+		return JpegOptions{}
+	case FormatGif:
+		return GifOptions{}
+	case FormatSixel:
+		return SixelOptions{}
+	case FormatSvg:
+		return SvgOptions{}
+	case FormatCanvas:
+		return CanvasOptions{Standalone: true}
+	case FormatPdf:
+		return PdfOptions{}
+	case FormatPdfCairo:
+		return PdfCairoOptions{}
+	case FormatPostscript:
+		return PostscriptOptions{}
+	case FormatEps:
+		return EpsOptions{}
+	case FormatDumb:
+		return DumbOptions{}
+	case FormatLatex:
+		return LatexOptions{}
+	case FormatTikz:
+		return TikzOptions{}
+	default:
+		return rawTerminal(format)
+	}
+}
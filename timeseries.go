@@ -0,0 +1,181 @@
+package glot
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// epochSeconds converts t to the number gnuplot uses for times: the seconds
+// since the Unix epoch. gnuplot shows every time in UTC, so the offset of
+// loc is added to make the time read as a wall clock time of loc.
+func epochSeconds(t time.Time, loc *time.Location) float64 {
+	_, offset := t.In(loc).Zone()
+	return float64(t.Unix()+int64(offset)) + float64(t.Nanosecond())/1e9
+}
+
+// AddTimeSeries adds a point group whose x coordinates are times.
+// The values hold the other coordinates in any of the formats accepted by
+// AddPointGroup for a single coordinate or for several ones.
+// The x axis is switched to time data if it was not done by SetTimeAxis.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.SetTimeAxis(glot.AxisX, "%H:%M")
+//	plot.AddTimeSeries("latency", glot.StyleLines, timestamps, []float64{12, 15, 11})
+func (plot *plot) AddTimeSeries(name string, style Style, times []time.Time, values any, opts ...PointGroupOption) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	err := plot.checkNewName(name)
+	if err != nil {
+		return err
+	}
+	columns, err := plot.timeColumns(name, times, values, style)
+	if err != nil {
+		return err
+	}
+	curve := &pointGroup{name: name, dimensions: len(columns), data: values, set: true, style: string(style)}
+	curve.options.apply(opts)
+	err = curve.options.check(plot.dimensions)
+	if err != nil {
+		return err
+	}
+
+	curve.columns = columns
+	curve.timeColumn = true
+	err = plot.addPointGroup(curve)
+	if err != nil {
+		return err
+	}
+	if plot.timeAxes[AxisX] {
+		return nil
+	}
+	// the axis is switched only for a time series gnuplot accepted
+	err = plot.setTimeAxis(AxisX, "")
+	if err != nil {
+		return err
+	}
+	return plot.replot()
+}
+
+// timeColumns returns the times as seconds since the Unix epoch followed by
+// the columns of the values, one value per time.
+func (plot *plot) timeColumns(name string, times []time.Time, values any, style Style) ([][]float64, error) {
+	valueColumns, err := castData(values)
+	if err != nil {
+		return nil, err
+	}
+	for _, column := range valueColumns {
+		if len(column) != len(times) {
+			return nil, &GnuplotError{err: fmt.Sprintf("the time series %s has %d times and %d values", name, len(times), len(column))}
+		}
+	}
+	seconds := make([]float64, len(times))
+	for i, t := range times {
+		seconds[i] = epochSeconds(t, plot.location)
+	}
+	columns := append([][]float64{seconds}, valueColumns...)
+	return columns, plot.checkColumns(columns, style)
+}
+
+// UpdateTimeSeries replaces the times and the values of a point group added
+// by AddTimeSeries, keeping its name, style and position in the plot.
+//
+// Usage
+//
+//	plot.AddTimeSeries("latency", glot.StyleLines, timestamps, []float64{12, 15, 11})
+//	plot.UpdateTimeSeries("latency", newTimestamps, []float64{13, 14, 12, 10})
+func (plot *plot) UpdateTimeSeries(name string, times []time.Time, values any) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	pointGroup, exists := plot.pointGroup[name]
+	if !exists {
+		return &GnuplotError{err: fmt.Sprintf("A curve with name %s does not exist.", name)}
+	}
+	if !pointGroup.timeColumn {
+		return &GnuplotError{err: fmt.Sprintf("the curve %s is not a time series, use UpdatePointGroup", name)}
+	}
+	columns, err := plot.timeColumns(name, times, values, Style(pointGroup.style))
+	if err != nil {
+		return err
+	}
+	return plot.replaceData(pointGroup, values, columns, 0)
+}
+
+// SetTimeAxis makes the axis show time data. The tic labels are formatted
+// according to format, which uses the strftime like syntax of gnuplot,
+// e.g. "%H:%M" or "%d/%m\n%H:%M". An empty format keeps the gnuplot default.
+//
+// Usage
+//
+//	plot, _ := glot.NewPlot(2, false)
+//	plot.SetTimeAxis(glot.AxisX, "%H:%M")
+func (plot *plot) SetTimeAxis(axis Axis, format string) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.setTimeAxis(axis, format)
+}
+
+func (plot *plot) setTimeAxis(axis Axis, format string) error {
+	if !axis.valid() {
+		return &GnuplotError{err: fmt.Sprintf("unknown axis '%s'", axis)}
+	}
+	err := plot.set(string(axis)+"data", fmt.Sprintf("set %sdata time", axis))
+	if err != nil {
+		return err
+	}
+	plot.timeAxes[axis] = true
+	if format == "" {
+		return nil
+	}
+	return plot.set("format "+string(axis), fmt.Sprintf("set format %s %s", axis, doubleQuote(format)))
+}
+
+// SetTimeFormat sets the format gnuplot uses to read times given as strings,
+// for example in raw commands. Point groups added by AddTimeSeries and time
+// bounds of ranges don't depend on it.
+func (plot *plot) SetTimeFormat(format string) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+	return plot.set("timefmt", "set timefmt "+doubleQuote(format))
+}
+
+// SetTimeLocation sets the time zone the times of the point groups and of
+// the range bounds added afterwards are shown in. UTC is used by default.
+//
+// Usage
+//
+//	loc, _ := time.LoadLocation("Europe/Berlin")
+//	plot.SetTimeLocation(loc)
+func (plot *plot) SetTimeLocation(loc *time.Location) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	if loc == nil {
+		return &GnuplotError{err: "time location must not be nil"}
+	}
+	plot.location = loc
+	return nil
+}
+
+// SetTimeTics places the major tics of a time axis at the given interval.
+//
+// Usage
+//
+//	plot.SetTimeTics(glot.AxisX, 15*time.Minute)
+func (plot *plot) SetTimeTics(axis Axis, interval time.Duration) error {
+	plot.mu.Lock()
+	defer plot.mu.Unlock()
+
+	if !axis.valid() {
+		return &GnuplotError{err: fmt.Sprintf("unknown axis '%s'", axis)}
+	}
+	if interval <= 0 {
+		return &GnuplotError{err: fmt.Sprintf("invalid tics interval %v", interval)}
+	}
+	return plot.set(string(axis)+"tics", fmt.Sprintf("set %stics %s", axis,
+		strconv.FormatFloat(interval.Seconds(), 'f', -1, 64)))
+}
@@ -0,0 +1,64 @@
+package glot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUpdateTimeSeriesKeepsTimes(t *testing.T) {
+	p, err := NewPlot(2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	times := []time.Time{start, start.Add(time.Minute), start.Add(2 * time.Minute)}
+	err = p.AddTimeSeries("latency", StyleLines, times, []float64{12, 15, 11})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.UpdatePointGroup("latency", []float64{1, 2, 3})
+	if err == nil {
+		t.Error("UpdatePointGroup replaced the data of a time series")
+	}
+	err = p.UpdateTimeSeries("latency", times[:2], []float64{13, 14})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	columns := p.(*plot).pointGroup["latency"].columns
+	if len(columns) != 2 || len(columns[0]) != 2 {
+		t.Fatalf("got columns %v, want the times and the values of 2 points", columns)
+	}
+	if columns[0][1] != float64(start.Add(time.Minute).Unix()) || columns[1][1] != 14 {
+		t.Errorf("got the point (%v, %v), want (%v, 14)", columns[0][1], columns[1][1], start.Add(time.Minute).Unix())
+	}
+}
+
+func TestAddTimeSeriesChecksLengths(t *testing.T) {
+	plot, err := NewPlot(2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plot.Close()
+
+	times := []time.Time{time.Unix(0, 0), time.Unix(60, 0)}
+	tests := map[string]any{
+		"fewer values": []float64{1},
+		"more values":  []float64{1, 2, 3},
+		"short column": [][]float64{{1, 2}, {3}},
+	}
+	for name, values := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := plot.AddTimeSeries(name, StyleLines, times, values)
+			if err == nil {
+				t.Errorf("the values %v were accepted for %d times", values, len(times))
+			}
+		})
+	}
+	err = plot.UpdateTimeSeries("missing", times, []float64{1, 2})
+	if err == nil {
+		t.Error("updated a time series that doesn't exist")
+	}
+}